@@ -0,0 +1,425 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/config"
+	"github.com/ruziba3vich/tokenizer/internal/license"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/pkg/helper"
+	"github.com/ruziba3vich/tokenizer/internal/pkg/signer"
+	"github.com/ruziba3vich/tokenizer/internal/service"
+)
+
+const usage = `usage:
+  main                                   run the http server and the telegram bot
+  main license issue -email E -hwid H    write a signed offline license
+        [-valid-for 720h] [-entitlements a,b] [-out license.json]
+  main license verify -file license.json check a license file against the configured signing keys
+        [-jwks jwks.json]                or against a saved copy of the published keys
+  main plan set -name N [-features a,b] [-limits k=v,k=v] [-duration-days D]
+                                         create or update a plan
+  main plan list                         list plans
+  main plan assign -email E -name N      move a user to a plan
+  main org set -name N -seats S          create an organization or change its seat count
+  main org assign -email E -name N       add a user to an organization's seat pool
+  main user role -email E -role R        set the role (user, support, admin) of a user,
+                                         e.g. to seed the first admin
+  main revoke -id J | -email E [-hwid H] [-reason R]
+                                         revoke one approval, or every approval of a user or device`
+
+// runCommand executes a one-off administrative command instead of starting the server
+func runCommand(args []string) error {
+	switch args[0] {
+	case "license":
+		return licenseCommand(args[1:])
+	case "plan":
+		return planCommand(args[1:])
+	case "org":
+		return orgCommand(args[1:])
+	case "user":
+		return userCommand(args[1:])
+	case "revoke":
+		return revokeCommand(args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+func licenseCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "issue":
+		return licenseIssue(args[1:])
+	case "verify":
+		return licenseVerify(args[1:])
+	default:
+		return fmt.Errorf("unknown license command %q\n%s", args[0], usage)
+	}
+}
+
+func licenseIssue(args []string) error {
+	fs := flag.NewFlagSet("license issue", flag.ContinueOnError)
+	email := fs.String("email", "", "email of the licensed user")
+	hwid := fs.String("hwid", "", "hardware id of the licensed machine")
+	validFor := fs.Duration("valid-for", 0, "validity of the license, at most and by default OFFLINE_LICENSE_VALIDITY")
+	entitlements := fs.String("entitlements", "", "comma separated entitlements (default: the features of the user's plan)")
+	out := fs.String("out", "license.json", "file to write, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || *hwid == "" {
+		return errors.New("license issue: -email and -hwid are required")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	ring, err := NewKeyring(cfg)
+	if err != nil {
+		return err
+	}
+	svc, err := newCommandService()
+	if err != nil {
+		return err
+	}
+
+	user, err := svc.GetUserByEmail(*email)
+	if err != nil {
+		return err
+	}
+
+	validity := cfg.Licenses.Validity
+	if *validFor > 0 {
+		validity = *validFor
+	}
+	granted := cfg.Licenses.Entitlements
+	if user.Plan != nil {
+		granted = user.Plan.Features
+	}
+	if *entitlements != "" {
+		granted = splitList(*entitlements)
+	}
+
+	claims, err := svc.IssueLicense(user, *hwid, granted, validity)
+	if err != nil {
+		return err
+	}
+	file, err := license.Sign(ring, *claims)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("license %s for %s written to %s, valid until %s\n",
+		claims.ID, user.Email, *out, time.Unix(claims.NotAfter, 0).UTC().Format(time.RFC3339))
+	return nil
+}
+
+func licenseVerify(args []string) error {
+	fs := flag.NewFlagSet("license verify", flag.ContinueOnError)
+	path := fs.String("file", "license.json", "license file to check")
+	jwksPath := fs.String("jwks", "", "saved copy of /.well-known/jwks.json to check against instead of the configured keys")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	keys, err := verificationKeys(*jwksPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(*path)
+	if err != nil {
+		return err
+	}
+	var file license.File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", *path, err)
+	}
+
+	claims, err := license.Verify(keys, &file, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("license %s is valid: user %s, hwid %s, entitlements [%s], until %s\n",
+		claims.ID, claims.Email, claims.HWID, strings.Join(claims.Entitlements, ", "),
+		time.Unix(claims.NotAfter, 0).UTC().Format(time.RFC3339))
+	return nil
+}
+
+// verificationKeys reads the public keys from a JWKS file, or else loads the
+// configured signing keys without requiring the rest of the server configuration
+func verificationKeys(jwksPath string) (signer.CompactVerifier, error) {
+	if jwksPath == "" {
+		cfg, err := config.LoadSigning()
+		if err != nil {
+			return nil, err
+		}
+		return loadKeyring(cfg)
+	}
+
+	data, err := os.ReadFile(jwksPath)
+	if err != nil {
+		return nil, err
+	}
+	var set signer.JWKSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", jwksPath, err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("%s has no keys", jwksPath)
+	}
+	return set, nil
+}
+
+func planCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	fs := flag.NewFlagSet("plan "+args[0], flag.ContinueOnError)
+	name := fs.String("name", "", "plan name")
+	features := fs.String("features", "", "comma separated features")
+	limits := fs.String("limits", "", "comma separated name=value limits")
+	email := fs.String("email", "", "email of the user to assign")
+	durationDays := fs.Int("duration-days", 0, "days of access granted by registration or renewal, 0 never expires")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	svc, err := newCommandService()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "set":
+		if *name == "" {
+			return errors.New("plan set: -name is required")
+		}
+		plan := models.Plan{Name: *name, Features: splitList(*features), Limits: map[string]int{}, DurationDays: *durationDays}
+		for _, item := range splitList(*limits) {
+			key, value, _ := strings.Cut(item, "=")
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("plan set: limit %q is not name=integer", item)
+			}
+			plan.Limits[key] = n
+		}
+		if err := svc.SavePlan(&plan); err != nil {
+			return err
+		}
+		fmt.Printf("plan %s saved\n", plan.Name)
+		return nil
+	case "list":
+		plans, err := svc.ListPlans()
+		if err != nil {
+			return err
+		}
+		for _, plan := range plans {
+			fmt.Printf("%-20s days=%d features=[%s] limits=%v\n", plan.Name, plan.DurationDays, strings.Join(plan.Features, ","), plan.Limits)
+		}
+		return nil
+	case "assign":
+		if *name == "" || *email == "" {
+			return errors.New("plan assign: -email and -name are required")
+		}
+		user, err := svc.GetUserByEmail(*email)
+		if err != nil {
+			return err
+		}
+		plan, err := svc.GetPlanByName(*name)
+		if err != nil {
+			return err
+		}
+		if err := svc.SetUserPlan(user.ID, plan.ID); err != nil {
+			return err
+		}
+		fmt.Printf("%s moved to plan %s\n", user.Email, plan.Name)
+		return nil
+	default:
+		return fmt.Errorf("unknown plan command %q\n%s", args[0], usage)
+	}
+}
+
+func orgCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	fs := flag.NewFlagSet("org "+args[0], flag.ContinueOnError)
+	name := fs.String("name", "", "organization name")
+	seats := fs.Int("seats", 0, "number of concurrent seats")
+	email := fs.String("email", "", "email of the user to assign")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	svc, err := newCommandService()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "set":
+		if *name == "" || *seats < 1 {
+			return errors.New("org set: -name and a positive -seats are required")
+		}
+		org := models.Organization{Name: *name, Seats: *seats}
+		if err := svc.SaveOrganization(&org); err != nil {
+			return err
+		}
+		fmt.Printf("organization %s saved with %d seats\n", org.Name, org.Seats)
+		return nil
+	case "assign":
+		if *name == "" || *email == "" {
+			return errors.New("org assign: -email and -name are required")
+		}
+		user, err := svc.GetUserByEmail(*email)
+		if err != nil {
+			return err
+		}
+		org, err := svc.GetOrganizationByName(*name)
+		if err != nil {
+			return err
+		}
+		if err := svc.SetUserOrganization(user.ID, org.ID); err != nil {
+			return err
+		}
+		fmt.Printf("%s added to organization %s\n", user.Email, org.Name)
+		return nil
+	default:
+		return fmt.Errorf("unknown org command %q\n%s", args[0], usage)
+	}
+}
+
+func userCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	fs := flag.NewFlagSet("user "+args[0], flag.ContinueOnError)
+	email := fs.String("email", "", "email of the user")
+	role := fs.String("role", "", "user, support or admin")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "role":
+		if *email == "" || !models.ValidRole(*role) {
+			return errors.New("user role: -email and a -role of user, support or admin are required")
+		}
+		svc, err := newCommandService()
+		if err != nil {
+			return err
+		}
+		user, err := svc.GetUserByEmail(*email)
+		if err != nil {
+			return err
+		}
+		if err := svc.SetUserRole(user.ID, *role); err != nil {
+			return err
+		}
+		fmt.Printf("%s is now %s\n", user.Email, *role)
+		return nil
+	default:
+		return fmt.Errorf("unknown user command %q\n%s", args[0], usage)
+	}
+}
+
+func revokeCommand(args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ContinueOnError)
+	id := fs.String("id", "", "jti of the approval to revoke")
+	email := fs.String("email", "", "email of the user whose approvals are revoked")
+	hwid := fs.String("hwid", "", "limit the revocation to this device of the user")
+	reason := fs.String("reason", "", "why the approvals are revoked")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*id == "") == (*email == "") || (*hwid != "" && *email == "") {
+		return errors.New("revoke: either -id or -email (with an optional -hwid) is required")
+	}
+
+	svc, err := newCommandService()
+	if err != nil {
+		return err
+	}
+
+	if *id != "" {
+		if _, err := svc.RevokeApproval(*id, *reason); err != nil {
+			return err
+		}
+		fmt.Printf("approval %s revoked\n", *id)
+		return nil
+	}
+
+	user, err := svc.GetUserByEmail(*email)
+	if err != nil {
+		return err
+	}
+	if *hwid != "" {
+		if _, err := svc.RevokeDevice(user.ID, *hwid, *reason); err != nil {
+			return err
+		}
+		fmt.Printf("approvals of %s on %s revoked\n", user.Email, *hwid)
+		return nil
+	}
+	if _, err := svc.RevokeUser(user.ID, *reason); err != nil {
+		return err
+	}
+	fmt.Printf("approvals of %s revoked\n", user.Email)
+	return nil
+}
+
+// newCommandService wires the service the same way the server does
+func newCommandService() (*service.Service, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	logger, err := NewLogger(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := helper.NewDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return service.NewService(db, logger, cfg), nil
+}
+
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
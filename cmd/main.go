@@ -1,15 +1,15 @@
 package main
 
 import (
-	"crypto/rsa"
-	"crypto/x509"
+	"crypto/ed25519"
 	"encoding/json"
-	"encoding/pem"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -18,44 +18,103 @@ import (
 
 	lgg "github.com/ruziba3vich/prodonik_lgger"
 	_ "github.com/ruziba3vich/tokenizer/docs"
+	"github.com/ruziba3vich/tokenizer/internal/config"
 	handler "github.com/ruziba3vich/tokenizer/internal/http"
 	"github.com/ruziba3vich/tokenizer/internal/models"
 	"github.com/ruziba3vich/tokenizer/internal/pkg/helper"
+	"github.com/ruziba3vich/tokenizer/internal/pkg/signer"
 	"github.com/ruziba3vich/tokenizer/internal/service"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/fx"
 )
 
-func NewLogger() (*lgg.Logger, error) {
-	return lgg.NewLogger("./app.log")
+func NewLogger(cfg *config.Config) (*lgg.Logger, error) {
+	return lgg.NewLogger(cfg.Log.File)
 }
 
-func StartServer(h *handler.Handler) {
+func StartServer(h *handler.Handler, cfg *config.Config) {
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-On-Behalf-Of"}
 
 	router := gin.Default()
 	router.Use(cors.New(config))
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	router.POST("/generate-url", h.GenerateOneTimeLink)
+	router.POST("/generate-url", h.Authorize(models.PermKeysCreate), h.GenerateOneTimeLink)
 	router.POST("/register", h.RegisterUser)
 	router.POST("/login", h.Login)
+	router.POST("/renew", h.Renew)
+	router.POST("/token/refresh", h.RefreshToken)
+	router.POST("/logout", h.Logout)
+	router.POST("/password/reset", h.ResetPassword)
+	router.POST("/verify", h.VerifyApproval)
+	router.POST("/lease/heartbeat", h.LeaseHeartbeat)
+	router.POST("/lease/release", h.ReleaseLease)
+	router.GET("/.well-known/jwks.json", h.JWKS)
+	router.GET("/revocations", h.RevocationList)
 
-	if err := router.Run(":7777"); err != nil {
+	me := router.Group("/me", h.BearerAuth())
+	me.GET("", h.GetMe)
+	me.PATCH("", h.UpdateMe)
+	me.DELETE("", h.DeleteMe)
+
+	devices := router.Group("/devices", h.BearerAuth())
+	devices.GET("", h.ListDevices)
+	devices.DELETE("/:id", h.DeactivateDevice)
+
+	router.POST("/licenses", h.BearerAuth(), h.IssueLicense)
+
+	admin := router.Group("/admin")
+	admin.GET("/users", h.Authorize(models.PermUsersRead), h.AdminSearchUsers)
+	admin.GET("/users/:id", h.Authorize(models.PermUsersRead), h.AdminGetUser)
+	admin.DELETE("/users/:id", h.Authorize(models.PermUsersDelete), h.AdminDeleteUser)
+	admin.POST("/users/:id/disable", h.Authorize(models.PermUsersManage), h.AdminDisableUser)
+	admin.POST("/users/:id/enable", h.Authorize(models.PermUsersManage), h.AdminEnableUser)
+	admin.POST("/users/:id/password-reset", h.Authorize(models.PermUsersManage), h.AdminForcePasswordReset)
+	admin.PUT("/users/:id/role", h.Authorize(models.PermRolesManage), h.AdminSetUserRole)
+	admin.GET("/users/:id/devices", h.Authorize(models.PermDevicesManage), h.AdminListUserDevices)
+	admin.DELETE("/users/:id/devices/:device", h.Authorize(models.PermDevicesManage), h.AdminReleaseUserDevice)
+	admin.POST("/revocations", h.Authorize(models.PermApprovalsRevoke), h.AdminRevoke)
+	admin.GET("/keys", h.Authorize(models.PermKeysRead), h.AdminListKeys)
+	admin.GET("/keys/:key", h.Authorize(models.PermKeysRead), h.AdminGetKey)
+	admin.DELETE("/keys/:key", h.Authorize(models.PermKeysRevoke), h.AdminRevokeKey)
+	admin.GET("/keys/:key/redemptions", h.Authorize(models.PermKeysRead), h.AdminKeyRedemptions)
+
+	if err := router.Run(cfg.Server.Addr()); err != nil {
 		log.Fatal("failed to run server:", err)
 	}
 }
 
+// @title Tokenizer API
+// @version 1.0
+// @description Issues invitation keys, registers users and signs device approvals and licenses.
+// @BasePath /
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token in the form "Bearer <token>".
 func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	app := fx.New(
 		fx.Provide(
+			config.Load,
 			NewLogger,
 			helper.NewDB,
 			service.NewService,
-			loadPrivateKey,
+			NewKeyring,
 			handler.NewHandler,
 		),
 		fx.Invoke(
@@ -67,14 +126,14 @@ func main() {
 	app.Run()
 }
 
-func StartGoBot() {
-	go StartBot()
+func StartGoBot(cfg *config.Config) {
+	go StartBot(cfg.Telegram)
 }
 
-func StartBot() {
+func StartBot(cfg config.TelegramConfig) {
 	fmt.Println("--------------------- THE BOT IS GOING TO START ------------------")
 	pref := telebot.Settings{
-		Token:  "7217692907:AAHaBAN4efeuXqbTr54POoHAo8-8LQhxdsc",
+		Token:  cfg.Token,
 		Poller: &telebot.LongPoller{Timeout: 10 * time.Second},
 	}
 
@@ -105,8 +164,20 @@ func StartBot() {
 	// --- Existing: /generate_key handler ---
 	bot.Handle("/generate_key", func(c telebot.Context) error {
 		log.Printf("request received: /generate_key from %d %s", c.Sender().ID, c.Sender().LastName)
+		if !slices.Contains(cfg.AdminIDs, c.Sender().ID) {
+			log.Printf("refused /generate_key from %d: not a telegram admin", c.Sender().ID)
+			return c.Send("You are not allowed to generate keys.")
+		}
+
+		req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(cfg.APIURL, "/")+"/generate-url", nil)
+		if err != nil {
+			return c.Send("Failed to contact API: " + err.Error())
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", cfg.APIKey)
+		req.Header.Set("X-On-Behalf-Of", "telegram:"+strconv.FormatInt(c.Sender().ID, 10))
 
-		resp, err := http.Post("http://168.119.255.188:7777/generate-url", "application/json", nil)
+		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
 			log.Printf("Error contacting API: %v", err)
 			return c.Send("Failed to contact API: " + err.Error())
@@ -141,33 +212,54 @@ func StartBot() {
 	bot.Start() // This call is blocking and keeps the bot running
 }
 
-// Load private key from PEM file
-func loadPrivateKey() (*rsa.PrivateKey, error) {
-	data, err := os.ReadFile("private_key.pem")
-	if err != nil {
-		return nil, err
-	}
-	block, _ := pem.Decode(data)
-	if block == nil {
-		return nil, errors.New("invalid PEM format")
-	}
+// NewKeyring loads every configured signing key. Without an explicit key list
+// the single key at PrivateKeyPath becomes the active one
+func NewKeyring(cfg *config.Config) (*signer.Keyring, error) {
+	return loadKeyring(&cfg.Signing)
+}
 
-	var parsedKey any
-	if block.Type == "PRIVATE KEY" {
-		parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
-	} else if block.Type == "RSA PRIVATE KEY" {
-		parsedKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
-	} else {
-		return nil, errors.New("unsupported key type: " + block.Type)
+func loadKeyring(cfg *config.SigningConfig) (*signer.Keyring, error) {
+	keys := cfg.Keys
+	active := cfg.ActiveKeyID
+	if len(keys) == 0 {
+		keys = []config.SigningKeyConfig{{ID: cfg.KeyID, Path: cfg.PrivateKeyPath}}
 	}
 
-	if err != nil {
-		return nil, err
+	ring := signer.NewKeyring()
+	for _, k := range keys {
+		key, err := signer.LoadPrivateKey(k.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing key %s: %w", k.Path, err)
+		}
+
+		alg := k.Algorithm
+		if alg == "" {
+			alg = cfg.Algorithm
+			if _, ok := key.(ed25519.PrivateKey); ok {
+				alg = signer.EdDSA
+			}
+		}
+		s, err := signer.New(alg, key)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", k.Path, err)
+		}
+
+		kid := k.ID
+		if kid == "" {
+			if kid, err = signer.Thumbprint(s.Public()); err != nil {
+				return nil, err
+			}
+		}
+		if err := ring.Add(kid, s); err != nil {
+			return nil, err
+		}
+		if active == "" {
+			active = kid
+		}
 	}
 
-	rsaKey, ok := parsedKey.(*rsa.PrivateKey)
-	if !ok {
-		return nil, errors.New("not RSA private key")
+	if err := ring.SetActive(active); err != nil {
+		return nil, err
 	}
-	return rsaKey, nil
+	return ring, nil
 }
@@ -0,0 +1,559 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting of the service. Values are read from an optional
+// YAML file (CONFIG_FILE, config.yaml by default) and then overridden by environment variables
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Telegram    TelegramConfig    `yaml:"telegram"`
+	Signing     SigningConfig     `yaml:"signing"`
+	Links       LinksConfig       `yaml:"links"`
+	Devices     DevicesConfig     `yaml:"devices"`
+	Licenses    LicensesConfig    `yaml:"licenses"`
+	Trial       TrialConfig       `yaml:"trial"`
+	Leases      LeasesConfig      `yaml:"leases"`
+	Revocations RevocationsConfig `yaml:"revocations"`
+	Sessions    SessionsConfig    `yaml:"sessions"`
+	Admin       AdminConfig       `yaml:"admin"`
+	Log         LogConfig         `yaml:"log"`
+}
+
+type ServerConfig struct {
+	Port string `yaml:"port"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+}
+
+type TelegramConfig struct {
+	Token string `yaml:"token"`
+	// APIURL is the base url the bot uses to reach this service
+	APIURL string `yaml:"api_url"`
+	// APIKey is the admin api key the bot authenticates with; it must be one of Admin.APIKeys
+	APIKey string `yaml:"api_key"`
+	// AdminIDs are the telegram user ids allowed to mint invitation keys through the bot
+	AdminIDs []int64 `yaml:"admin_ids"`
+}
+
+type SigningConfig struct {
+	PrivateKeyPath string `yaml:"private_key_path"`
+	// KeyID names the key at PrivateKeyPath; its RFC 7638 thumbprint is used when empty
+	KeyID string `yaml:"key_id"`
+	// Algorithm is RS256 or PS256 for RSA keys; Ed25519 keys always sign with EdDSA
+	Algorithm string `yaml:"algorithm"`
+	// Keys replaces PrivateKeyPath when set, so retired keys stay published while clients rotate
+	Keys []SigningKeyConfig `yaml:"keys"`
+	// ActiveKeyID selects the key in Keys that signs new payloads
+	ActiveKeyID string `yaml:"active_key_id"`
+	// ApprovalTTL is how long a signed login approval stays valid
+	ApprovalTTL time.Duration `yaml:"approval_ttl"`
+}
+
+type SigningKeyConfig struct {
+	ID   string `yaml:"id"`
+	Path string `yaml:"path"`
+	// Algorithm overrides SigningConfig.Algorithm for this key
+	Algorithm string `yaml:"algorithm"`
+}
+
+type LinksConfig struct {
+	// BaseURL is prepended to invitation keys to build the shareable link
+	BaseURL string `yaml:"base_url"`
+}
+
+type DevicesConfig struct {
+	// MaxPerUser is how many active hardware ids one account may sign in from
+	MaxPerUser int `yaml:"max_per_user"`
+	// ReleaseCooldown is the minimum time between two device deactivations by the same user
+	ReleaseCooldown time.Duration `yaml:"release_cooldown"`
+}
+
+type LicensesConfig struct {
+	// Validity is how long an offline license file stays valid
+	Validity time.Duration `yaml:"validity"`
+	// Entitlements are embedded in offline licenses of users without a plan
+	Entitlements []string `yaml:"entitlements"`
+}
+
+type TrialConfig struct {
+	// Days is the length of the trial granted by trial keys
+	Days int `yaml:"days"`
+	// Plan names the plan with the reduced entitlements of trial accounts; empty grants none
+	Plan string `yaml:"plan"`
+}
+
+type LeasesConfig struct {
+	// TTL is how long a concurrent seat stays checked out without a heartbeat
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type RevocationsConfig struct {
+	// ListInterval is how often the signed revocation list is regenerated and how long clients may cache it
+	ListInterval time.Duration `yaml:"list_interval"`
+}
+
+type SessionsConfig struct {
+	// AccessTTL is how long an access token issued at login or refresh stays valid
+	AccessTTL time.Duration `yaml:"access_ttl"`
+	// RefreshTTL is how long an unused refresh token can be exchanged for a new pair
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+}
+
+type AdminConfig struct {
+	// APIKeys authenticate service principals, such as the telegram bot, on admin endpoints
+	APIKeys []APIKeyConfig `yaml:"api_keys"`
+}
+
+type APIKeyConfig struct {
+	// Name identifies the principal, e.g. in the creator of invitation keys
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+	// Permissions are the admin actions the key may perform, like those of a role;
+	// a key without any may only create invitation keys
+	Permissions []models.Permission `yaml:"permissions"`
+}
+
+type LogConfig struct {
+	File string `yaml:"file"`
+}
+
+func defaults() *Config {
+	return &Config{
+		Server:      ServerConfig{Port: "7777"},
+		Database:    DatabaseConfig{Port: "5432", SSLMode: "disable"},
+		Signing:     SigningConfig{PrivateKeyPath: "private_key.pem", Algorithm: "RS256", ApprovalTTL: 15 * time.Minute},
+		Links:       LinksConfig{BaseURL: "https://fintrack.vintorum.com/key/"},
+		Devices:     DevicesConfig{MaxPerUser: 3, ReleaseCooldown: 72 * time.Hour},
+		Licenses:    LicensesConfig{Validity: 30 * 24 * time.Hour},
+		Trial:       TrialConfig{Days: 14},
+		Leases:      LeasesConfig{TTL: 5 * time.Minute},
+		Revocations: RevocationsConfig{ListInterval: 5 * time.Minute},
+		Sessions:    SessionsConfig{AccessTTL: 15 * time.Minute, RefreshTTL: 30 * 24 * time.Hour},
+		Log:         LogConfig{File: "./app.log"},
+	}
+}
+
+// Load builds the configuration from defaults, the optional YAML file and the environment, and validates it
+func Load() (*Config, error) {
+	cfg, err := read()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Telegram.APIURL == "" {
+		cfg.Telegram.APIURL = "http://localhost:" + cfg.Server.Port
+	}
+	for i := range cfg.Admin.APIKeys {
+		if len(cfg.Admin.APIKeys[i].Permissions) == 0 {
+			cfg.Admin.APIKeys[i].Permissions = []models.Permission{models.PermKeysCreate}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadSigning reads the configuration like Load but validates only the signing
+// section, for commands that merely verify signatures and need no database or bot
+func LoadSigning() (*SigningConfig, error) {
+	cfg, err := read()
+	if err != nil {
+		return nil, err
+	}
+	if err := errors.Join(cfg.Signing.validate()...); err != nil {
+		return nil, err
+	}
+	return &cfg.Signing, nil
+}
+
+// read applies the config file and the environment over the defaults
+func read() (*Config, error) {
+	cfg := defaults()
+
+	path, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		path = "config.yaml"
+	}
+	if err := cfg.loadFile(path, explicit); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !required {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	envString(&c.Server.Port, "SERVER_PORT")
+
+	envString(&c.Database.Host, "DB_HOST")
+	envString(&c.Database.Port, "DB_PORT")
+	envString(&c.Database.User, "DB_USER")
+	envString(&c.Database.Password, "DB_PASSWORD")
+	envString(&c.Database.Name, "DB_NAME")
+	envString(&c.Database.SSLMode, "DB_SSLMODE")
+
+	envString(&c.Telegram.Token, "TELEGRAM_TOKEN")
+	envString(&c.Telegram.APIURL, "BOT_API_URL")
+	envString(&c.Telegram.APIKey, "BOT_API_KEY")
+
+	envString(&c.Signing.PrivateKeyPath, "PRIVATE_KEY_PATH")
+	envString(&c.Signing.Algorithm, "SIGNING_ALGORITHM")
+	envString(&c.Signing.KeyID, "SIGNING_KEY_ID")
+	envString(&c.Signing.ActiveKeyID, "SIGNING_ACTIVE_KEY_ID")
+	// SIGNING_KEYS is a comma separated list of id=path pairs
+	if v, ok := os.LookupEnv("SIGNING_KEYS"); ok {
+		c.Signing.Keys = nil
+		for _, entry := range strings.Split(v, ",") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			id, path, _ := strings.Cut(entry, "=")
+			c.Signing.Keys = append(c.Signing.Keys, SigningKeyConfig{ID: strings.TrimSpace(id), Path: strings.TrimSpace(path)})
+		}
+	}
+
+	// ADMIN_API_KEYS is a comma separated list of name=key pairs
+	if v, ok := os.LookupEnv("ADMIN_API_KEYS"); ok {
+		c.Admin.APIKeys = nil
+		for _, entry := range strings.Split(v, ",") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			name, key, _ := strings.Cut(entry, "=")
+			c.Admin.APIKeys = append(c.Admin.APIKeys, APIKeyConfig{Name: strings.TrimSpace(name), Key: strings.TrimSpace(key)})
+		}
+	}
+
+	// ADMIN_API_KEY_PERMISSIONS is a comma separated list of name=perm|perm pairs
+	if v, ok := os.LookupEnv("ADMIN_API_KEY_PERMISSIONS"); ok {
+		for _, entry := range strings.Split(v, ",") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			name, perms, _ := strings.Cut(entry, "=")
+			i := slices.IndexFunc(c.Admin.APIKeys, func(k APIKeyConfig) bool { return k.Name == strings.TrimSpace(name) })
+			if i < 0 {
+				return fmt.Errorf("admin api key permissions (ADMIN_API_KEY_PERMISSIONS) name an unknown key %q", name)
+			}
+			c.Admin.APIKeys[i].Permissions = nil
+			for _, perm := range strings.Split(perms, "|") {
+				if perm = strings.TrimSpace(perm); perm != "" {
+					c.Admin.APIKeys[i].Permissions = append(c.Admin.APIKeys[i].Permissions, models.Permission(perm))
+				}
+			}
+		}
+	}
+
+	envString(&c.Links.BaseURL, "LINK_BASE_URL")
+
+	envList(&c.Licenses.Entitlements, "OFFLINE_LICENSE_ENTITLEMENTS")
+
+	envString(&c.Trial.Plan, "TRIAL_PLAN")
+
+	envString(&c.Log.File, "LOG_FILE")
+
+	return errors.Join(
+		envInt64List(&c.Telegram.AdminIDs, "TELEGRAM_ADMIN_IDS"),
+		envDuration(&c.Signing.ApprovalTTL, "APPROVAL_TTL"),
+		envInt(&c.Devices.MaxPerUser, "MAX_DEVICES_PER_USER"),
+		envDuration(&c.Devices.ReleaseCooldown, "DEVICE_RELEASE_COOLDOWN"),
+		envDuration(&c.Licenses.Validity, "OFFLINE_LICENSE_VALIDITY"),
+		envInt(&c.Trial.Days, "TRIAL_DAYS"),
+		envDuration(&c.Leases.TTL, "LEASE_TTL"),
+		envDuration(&c.Revocations.ListInterval, "REVOCATION_LIST_INTERVAL"),
+		envDuration(&c.Sessions.AccessTTL, "ACCESS_TOKEN_TTL"),
+		envDuration(&c.Sessions.RefreshTTL, "REFRESH_TOKEN_TTL"),
+	)
+}
+
+// Validate reports every missing or malformed required value at once
+func (c *Config) Validate() error {
+	var errs []error
+	required := map[string]string{
+		"server port (SERVER_PORT)":       c.Server.Port,
+		"database host (DB_HOST)":         c.Database.Host,
+		"database port (DB_PORT)":         c.Database.Port,
+		"database user (DB_USER)":         c.Database.User,
+		"database name (DB_NAME)":         c.Database.Name,
+		"telegram token (TELEGRAM_TOKEN)": c.Telegram.Token,
+		"bot api key (BOT_API_KEY)":       c.Telegram.APIKey,
+		"log file (LOG_FILE)":             c.Log.File,
+	}
+	for name, value := range required {
+		if strings.TrimSpace(value) == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+
+	if _, err := strconv.ParseUint(c.Server.Port, 10, 16); c.Server.Port != "" && err != nil {
+		errs = append(errs, fmt.Errorf("server port %q is not a valid port", c.Server.Port))
+	}
+	errs = append(errs, c.Signing.validate()...)
+	if c.Signing.ApprovalTTL <= 0 {
+		errs = append(errs, errors.New("approval ttl (APPROVAL_TTL) must be positive"))
+	}
+	if c.Devices.MaxPerUser < 1 {
+		errs = append(errs, errors.New("device limit (MAX_DEVICES_PER_USER) must be at least 1"))
+	}
+	if c.Devices.ReleaseCooldown < 0 {
+		errs = append(errs, errors.New("device release cooldown (DEVICE_RELEASE_COOLDOWN) must not be negative"))
+	}
+	if c.Licenses.Validity <= 0 {
+		errs = append(errs, errors.New("offline license validity (OFFLINE_LICENSE_VALIDITY) must be positive"))
+	}
+	if c.Trial.Days < 1 {
+		errs = append(errs, errors.New("trial length (TRIAL_DAYS) must be at least 1 day"))
+	}
+	if c.Leases.TTL <= 0 {
+		errs = append(errs, errors.New("seat lease ttl (LEASE_TTL) must be positive"))
+	}
+	if c.Revocations.ListInterval <= 0 {
+		errs = append(errs, errors.New("revocation list interval (REVOCATION_LIST_INTERVAL) must be positive"))
+	}
+	if c.Sessions.AccessTTL <= 0 {
+		errs = append(errs, errors.New("access token ttl (ACCESS_TOKEN_TTL) must be positive"))
+	}
+	if c.Sessions.RefreshTTL <= c.Sessions.AccessTTL {
+		errs = append(errs, errors.New("refresh token ttl (REFRESH_TOKEN_TTL) must be longer than the access token ttl"))
+	}
+	if err := validateURL(c.Links.BaseURL); err != nil {
+		errs = append(errs, fmt.Errorf("link base url (LINK_BASE_URL): %w", err))
+	}
+	if err := validateURL(c.Telegram.APIURL); err != nil {
+		errs = append(errs, fmt.Errorf("bot api url (BOT_API_URL): %w", err))
+	} else if u, _ := url.Parse(c.Telegram.APIURL); u.Scheme != "https" && !isLoopback(u.Hostname()) {
+		errs = append(errs, fmt.Errorf("bot api url (BOT_API_URL) %q must use https unless it points at this host", c.Telegram.APIURL))
+	}
+	if len(c.Telegram.AdminIDs) == 0 {
+		errs = append(errs, errors.New("telegram admin ids (TELEGRAM_ADMIN_IDS) are required; only they may mint keys through the bot"))
+	}
+	for _, id := range c.Telegram.AdminIDs {
+		if id <= 0 {
+			errs = append(errs, fmt.Errorf("telegram admin id (TELEGRAM_ADMIN_IDS) %d is not a valid user id", id))
+		}
+	}
+	errs = append(errs, c.Admin.validateKeys(c.Telegram.APIKey)...)
+
+	return errors.Join(errs...)
+}
+
+// validate checks the settings needed to load the signing keys
+func (s SigningConfig) validate() []error {
+	var errs []error
+	if len(s.Keys) == 0 && strings.TrimSpace(s.PrivateKeyPath) == "" {
+		errs = append(errs, errors.New("signing private key (PRIVATE_KEY_PATH) is required"))
+	}
+	if !validAlgorithm(s.Algorithm) {
+		errs = append(errs, fmt.Errorf("signing algorithm (SIGNING_ALGORITHM) %q must be RS256, PS256 or EdDSA", s.Algorithm))
+	}
+	return append(errs, s.validateKeys()...)
+}
+
+func (s SigningConfig) validateKeys() []error {
+	if len(s.Keys) == 0 {
+		return nil
+	}
+
+	var errs []error
+	seen := make(map[string]bool, len(s.Keys))
+	for i, key := range s.Keys {
+		if key.ID == "" || key.Path == "" {
+			errs = append(errs, fmt.Errorf("signing key #%d (SIGNING_KEYS) needs both an id and a path", i+1))
+		}
+		if seen[key.ID] {
+			errs = append(errs, fmt.Errorf("signing key id %q (SIGNING_KEYS) is used more than once", key.ID))
+		}
+		seen[key.ID] = true
+		if key.Algorithm != "" && !validAlgorithm(key.Algorithm) {
+			errs = append(errs, fmt.Errorf("signing key %q has unsupported algorithm %q", key.ID, key.Algorithm))
+		}
+	}
+
+	if s.ActiveKeyID == "" && len(s.Keys) > 1 {
+		errs = append(errs, errors.New("active signing key (SIGNING_ACTIVE_KEY_ID) is required when several keys are configured"))
+	} else if s.ActiveKeyID != "" && !seen[s.ActiveKeyID] {
+		errs = append(errs, fmt.Errorf("active signing key %q (SIGNING_ACTIVE_KEY_ID) is not among the configured keys", s.ActiveKeyID))
+	}
+	return errs
+}
+
+// minAPIKeyLength keeps admin api keys out of reach of guessing
+const minAPIKeyLength = 32
+
+// placeholderKey marks the sample keys of config.example.yaml, which must never reach a deployment
+const placeholderKey = "change-me"
+
+func (a AdminConfig) validateKeys(botKey string) []error {
+	var errs []error
+	seen := make(map[string]bool, len(a.APIKeys))
+	botKeyListed := false
+	for i, key := range a.APIKeys {
+		if key.Name == "" || key.Key == "" {
+			errs = append(errs, fmt.Errorf("admin api key #%d (ADMIN_API_KEYS) needs both a name and a key", i+1))
+			continue
+		}
+		if seen[key.Name] {
+			errs = append(errs, fmt.Errorf("admin api key name %q (ADMIN_API_KEYS) is used more than once", key.Name))
+		}
+		seen[key.Name] = true
+		if len(key.Key) < minAPIKeyLength {
+			errs = append(errs, fmt.Errorf("admin api key %q (ADMIN_API_KEYS) must be at least %d characters", key.Name, minAPIKeyLength))
+		}
+		if strings.Contains(strings.ToLower(key.Key), placeholderKey) {
+			errs = append(errs, fmt.Errorf("admin api key %q (ADMIN_API_KEYS) is the sample placeholder; generate a random secret", key.Name))
+		}
+		for _, perm := range key.Permissions {
+			if !models.ValidPermission(perm) {
+				errs = append(errs, fmt.Errorf("admin api key %q (ADMIN_API_KEY_PERMISSIONS) has unknown permission %q", key.Name, perm))
+			}
+		}
+		botKeyListed = botKeyListed || key.Key == botKey
+	}
+	if botKey != "" && !botKeyListed {
+		errs = append(errs, errors.New("bot api key (BOT_API_KEY) must be one of the admin api keys (ADMIN_API_KEYS)"))
+	}
+	return errs
+}
+
+// DSN returns the postgres connection string
+func (d DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		d.Host, d.User, d.Password, d.Name, d.Port, d.SSLMode)
+}
+
+// Addr returns the address the http server listens on
+func (s ServerConfig) Addr() string {
+	return ":" + s.Port
+}
+
+func envString(dst *string, key string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = v
+	}
+}
+
+// envList reads a comma separated list, dropping empty items
+func envList(dst *[]string, key string) {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	*dst = nil
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*dst = append(*dst, item)
+		}
+	}
+}
+
+func envInt(dst *int, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not an integer", key, v)
+	}
+	*dst = n
+	return nil
+}
+
+// envInt64List reads a comma separated list of integers, dropping empty items
+func envInt64List(dst *[]int64, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	*dst = nil
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", key, item)
+		}
+		*dst = append(*dst, n)
+	}
+	return nil
+}
+
+func envDuration(dst *time.Duration, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a duration", key, v)
+	}
+	*dst = d
+	return nil
+}
+
+func validAlgorithm(alg string) bool {
+	switch alg {
+	case "RS256", "PS256", "EdDSA":
+		return true
+	}
+	return false
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func validateURL(raw string) error {
+	if raw == "" {
+		return errors.New("is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute url", raw)
+	}
+	return nil
+}
@@ -0,0 +1,446 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/service"
+)
+
+// AdminUser is an account as support sees it
+type AdminUser struct {
+	User    *models.User    `json:"user"`
+	Devices []models.Device `json:"devices"`
+	License LicenseStatus   `json:"license"`
+}
+
+// LicenseStatus is the state of an account's subscription, as /login would report it
+type LicenseStatus struct {
+	Status        string     `json:"status" example:"APPROVED"`
+	Plan          string     `json:"plan,omitempty" example:"pro"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	RemainingDays int        `json:"remaining_days,omitempty" example:"7"`
+}
+
+// AdminSearchUsers godoc
+// @Summary Search users
+// @Description Finds accounts by case-insensitive substring. q matches email, username or phone; the other filters match their field. Requires users:read.
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param q query string false "Email, username or phone"
+// @Param email query string false "Email"
+// @Param username query string false "Username"
+// @Param phone query string false "Phone"
+// @Param page query int false "Page, from 1"
+// @Param page_size query int false "Users per page, at most 200"
+// @Success 200 {object} models.UserList
+// @Failure 400 {object} map[string]string "error: invalid page"
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Router /admin/users [get]
+func (h *Handler) AdminSearchUsers(c *gin.Context) {
+	filter := service.UserFilter{
+		Query:    c.Query("q"),
+		Email:    c.Query("email"),
+		Username: c.Query("username"),
+		Phone:    c.Query("phone"),
+	}
+	var err error
+	if filter.Page, filter.PageSize, err = pageQuery(c); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.service.SearchUsers(filter)
+	if err != nil {
+		h.logger.Println("SearchUsers error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// AdminGetUser godoc
+// @Summary Look up a user
+// @Description Returns an account with its plan, devices and license status. Requires users:read.
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} AdminUser
+// @Failure 400 {object} map[string]string "error: invalid user id"
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Failure 404 {object} map[string]string "error: user not found"
+// @Router /admin/users/{id} [get]
+func (h *Handler) AdminGetUser(c *gin.Context) {
+	userID, ok := idParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	user, err := h.service.GetUserByID(userID)
+	if err != nil {
+		h.respondUserError(c, "GetUserByID", err)
+		return
+	}
+	devices, err := h.service.ListDevices(userID)
+	if err != nil {
+		h.respondUserError(c, "ListDevices", err)
+		return
+	}
+
+	now := time.Now()
+	license := LicenseStatus{Status: StatusApproved, ExpiresAt: user.LicenseExpiresAt}
+	switch {
+	case service.LicenseExpired(user, now):
+		license.Status = StatusLicenseExpired
+	case user.Trial:
+		license.Status = StatusTrial
+		license.RemainingDays = service.RemainingDays(user, now)
+	}
+	if user.Plan != nil {
+		license.Plan = user.Plan.Name
+	}
+
+	c.JSON(http.StatusOK, AdminUser{User: user, Devices: devices, License: license})
+}
+
+// AdminDisableUser godoc
+// @Summary Disable a user
+// @Description Locks an account: logins are refused, its sessions end and its outstanding approvals are revoked. Requires users:manage.
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param payload body models.DisableUserPayload false "Reason"
+// @Success 200 {object} map[string]string "message: account disabled"
+// @Failure 400 {object} map[string]string "error: invalid request"
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Failure 404 {object} map[string]string "error: user not found"
+// @Router /admin/users/{id}/disable [post]
+func (h *Handler) AdminDisableUser(c *gin.Context) {
+	userID, ok := idParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if !h.mayManage(c, userID) {
+		return
+	}
+	var payload models.DisableUserPayload
+	if err := c.ShouldBindJSON(&payload); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.DisableUser(userID, payload.Reason); err != nil {
+		h.respondUserError(c, "DisableUser", err)
+		return
+	}
+
+	h.logger.Println("User", userID, "disabled by", currentPrincipal(c))
+	c.JSON(http.StatusOK, gin.H{"message": "account disabled"})
+}
+
+// AdminEnableUser godoc
+// @Summary Enable a user
+// @Description Unlocks a disabled account. Revoked approvals stay revoked; the user logs in again. Requires users:manage.
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string "message: account enabled"
+// @Failure 400 {object} map[string]string "error: invalid user id"
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Failure 404 {object} map[string]string "error: user not found"
+// @Router /admin/users/{id}/enable [post]
+func (h *Handler) AdminEnableUser(c *gin.Context) {
+	userID, ok := idParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if !h.mayManage(c, userID) {
+		return
+	}
+
+	if err := h.service.EnableUser(userID); err != nil {
+		h.respondUserError(c, "EnableUser", err)
+		return
+	}
+
+	h.logger.Println("User", userID, "enabled by", currentPrincipal(c))
+	c.JSON(http.StatusOK, gin.H{"message": "account enabled"})
+}
+
+// AdminForcePasswordReset godoc
+// @Summary Force a password reset
+// @Description Ends the sessions of an account and blocks its logins until a new password is set through /password/reset with the returned code. Hand the code to the user over a verified channel. Requires users:manage.
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.PasswordResetResponse
+// @Failure 400 {object} map[string]string "error: invalid user id"
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Failure 404 {object} map[string]string "error: user not found"
+// @Router /admin/users/{id}/password-reset [post]
+func (h *Handler) AdminForcePasswordReset(c *gin.Context) {
+	userID, ok := idParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if !h.mayManage(c, userID) {
+		return
+	}
+
+	code, expiresAt, err := h.service.ForcePasswordReset(userID)
+	if err != nil {
+		h.respondUserError(c, "ForcePasswordReset", err)
+		return
+	}
+
+	h.logger.Println("Password reset of user", userID, "forced by", currentPrincipal(c))
+	c.JSON(http.StatusOK, models.PasswordResetResponse{Code: code, ExpiresAt: expiresAt})
+}
+
+// AdminDeleteUser godoc
+// @Summary Delete a user
+// @Description Deletes an account with its devices, sessions and seat leases. Requires users:delete.
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string "message: account deleted"
+// @Failure 400 {object} map[string]string "error: invalid user id"
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Failure 404 {object} map[string]string "error: user not found"
+// @Router /admin/users/{id} [delete]
+func (h *Handler) AdminDeleteUser(c *gin.Context) {
+	userID, ok := idParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.service.DeleteUser(userID); err != nil {
+		h.respondUserError(c, "DeleteUser", err)
+		return
+	}
+
+	h.logger.Println("User", userID, "deleted by", currentPrincipal(c))
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted"})
+}
+
+// AdminSetUserRole godoc
+// @Summary Change a user's role
+// @Description Makes a user a plain user, support staff or admin. Requires roles:manage.
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param payload body models.RolePayload true "New role"
+// @Success 200 {object} map[string]string "message: role updated"
+// @Failure 400 {object} map[string]string "error: invalid request or unknown role"
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Failure 404 {object} map[string]string "error: user not found"
+// @Router /admin/users/{id}/role [put]
+func (h *Handler) AdminSetUserRole(c *gin.Context) {
+	userID, ok := idParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var payload models.RolePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.SetUserRole(userID, payload.Role); err != nil {
+		if errors.Is(err, service.ErrUnknownRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.respondUserError(c, "SetUserRole", err)
+		return
+	}
+
+	h.logger.Println("Role of user", userID, "set to", payload.Role, "by", currentPrincipal(c))
+	c.JSON(http.StatusOK, gin.H{"message": "role updated"})
+}
+
+// AdminListUserDevices godoc
+// @Summary List a user's devices
+// @Description Lists the machines attached to an account. Requires devices:manage.
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {array} models.Device
+// @Failure 400 {object} map[string]string "error: invalid user id"
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Router /admin/users/{id}/devices [get]
+func (h *Handler) AdminListUserDevices(c *gin.Context) {
+	userID, ok := idParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	devices, err := h.service.ListDevices(userID)
+	if err != nil {
+		h.logger.Println("ListDevices error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list devices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, devices)
+}
+
+// AdminReleaseUserDevice godoc
+// @Summary Release a user's device
+// @Description Deactivates one machine of an account without the self-service cooldown. Requires devices:manage.
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param device path int true "Device ID"
+// @Success 200 {object} map[string]string "message: device deactivated"
+// @Failure 400 {object} map[string]string "error: invalid id"
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Failure 404 {object} map[string]string "error: device not found"
+// @Router /admin/users/{id}/devices/{device} [delete]
+func (h *Handler) AdminReleaseUserDevice(c *gin.Context) {
+	userID, ok := idParam(c, "id")
+	deviceID, ok2 := idParam(c, "device")
+	if !ok || !ok2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if !h.mayManage(c, userID) {
+		return
+	}
+
+	if err := h.service.ReleaseDevice(userID, deviceID); err != nil {
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("ReleaseDevice error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to deactivate device"})
+		return
+	}
+
+	h.logger.Println("Device", deviceID, "of user", userID, "released by", currentPrincipal(c))
+	c.JSON(http.StatusOK, gin.H{"message": "device deactivated"})
+}
+
+// AdminRevoke godoc
+// @Summary Revoke approvals
+// @Description Revokes one approval or offline license by jti, or every approval and license issued so far to a user or one of their devices. The change reaches /verify at once and the revocation list with its next update. Requires approvals:revoke; support staff may only revoke for regular users.
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param payload body models.RevokePayload true "What to revoke"
+// @Success 201 {object} models.Revocation
+// @Failure 400 {object} map[string]string "error: invalid request"
+// @Failure 403 {object} map[string]string "error: permission denied or only admins may manage staff accounts"
+// @Failure 404 {object} map[string]string "error: user or device not found"
+// @Router /admin/revocations [post]
+func (h *Handler) AdminRevoke(c *gin.Context) {
+	var payload models.RevokePayload
+	if err := c.ShouldBindJSON(&payload); err != nil || (payload.ApprovalID == "") == (payload.UserID == 0) || (payload.Vhid != "" && payload.UserID == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "either jti or user_id (with an optional hwid) is required"})
+		return
+	}
+	if payload.UserID != 0 && !h.mayManage(c, payload.UserID) {
+		return
+	}
+
+	var (
+		revocation *models.Revocation
+		err        error
+	)
+	switch {
+	case payload.ApprovalID != "":
+		revocation, err = h.service.RevokeApproval(payload.ApprovalID, payload.Reason)
+	case payload.Vhid != "":
+		revocation, err = h.service.RevokeDevice(payload.UserID, payload.Vhid, payload.Reason)
+	default:
+		revocation, err = h.service.RevokeUser(payload.UserID, payload.Reason)
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("Revoke error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke"})
+		return
+	}
+
+	h.logger.Println("Revocation", revocation.ID, "created by", currentPrincipal(c))
+	c.JSON(http.StatusCreated, revocation)
+}
+
+// mayManage keeps support staff from acting on accounts of other staff, e.g.
+// taking over an admin through a forced password reset. Admins and api key
+// principals may manage everyone. On refusal the response is already written
+func (h *Handler) mayManage(c *gin.Context, userID uint) bool {
+	actor, ok := c.Get(userContextKey)
+	if !ok || actor.(*models.User).Role == models.RoleAdmin {
+		return true
+	}
+
+	target, err := h.service.GetUserByID(userID)
+	if err != nil {
+		h.respondUserError(c, "GetUserByID", err)
+		return false
+	}
+	if target.Role != models.RoleUser {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins may manage staff accounts"})
+		return false
+	}
+	return true
+}
+
+// respondUserError answers 404 for a missing user and logs anything else as an internal error
+func (h *Handler) respondUserError(c *gin.Context, op string, err error) {
+	if errors.Is(err, service.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Println(op, "error:", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process user"})
+}
+
+// idParam parses a numeric path parameter
+func idParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ruziba3vich/tokenizer/internal/service"
+)
+
+// ListDevices godoc
+// @Summary List my devices
+// @Description Lists the machines attached to the authenticated account
+// @Tags Devices
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} models.Device
+// @Failure 401 {object} map[string]string "error: authentication required"
+// @Router /devices [get]
+func (h *Handler) ListDevices(c *gin.Context) {
+	user := currentUser(c)
+
+	devices, err := h.service.ListDevices(user.ID)
+	if err != nil {
+		h.logger.Println("ListDevices error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list devices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, devices)
+}
+
+// DeactivateDevice godoc
+// @Summary Deactivate a device
+// @Description Releases one of the account's machines so another one can sign in. Releases are limited by a cooldown.
+// @Tags Devices
+// @Produce  json
+// @Security BearerAuth
+// @Param id path int true "Device ID"
+// @Success 200 {object} map[string]string "message: device deactivated"
+// @Failure 400 {object} map[string]string "error: invalid device id"
+// @Failure 401 {object} map[string]string "error: authentication required"
+// @Failure 404 {object} map[string]string "error: device not found"
+// @Failure 429 {object} map[string]string "error: device release cooldown has not passed"
+// @Router /devices/{id} [delete]
+func (h *Handler) DeactivateDevice(c *gin.Context) {
+	user := currentUser(c)
+
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid device id"})
+		return
+	}
+
+	nextRelease, err := h.service.DeactivateDevice(user.ID, uint(deviceID))
+	switch {
+	case errors.Is(err, service.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrReleaseCooldown):
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(*nextRelease).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       err.Error(),
+			"retry_after": nextRelease.UTC().Format(time.RFC3339),
+		})
+		return
+	case err != nil:
+		h.logger.Println("DeactivateDevice error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to deactivate device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "device deactivated"})
+}
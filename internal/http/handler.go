@@ -1,55 +1,110 @@
 package handler
 
 import (
-	"crypto"
-	"crypto/rsa"
-	"crypto/sha256"
+	"crypto/rand"
 	"encoding/base64"
-	"encoding/json"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	lgg "github.com/ruziba3vich/prodonik_lgger"
+	"github.com/ruziba3vich/tokenizer/internal/config"
 	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/pkg/signer"
 	"github.com/ruziba3vich/tokenizer/internal/service"
 )
 
 type Handler struct {
-	service    *service.Service
-	logger     *lgg.Logger
-	privateKey *rsa.PrivateKey
+	service *service.Service
+	logger  *lgg.Logger
+	keyring *signer.Keyring
+	cfg     *config.Config
+
+	revocations revocationCache
 }
 
-func NewHandler(service *service.Service, privateKey *rsa.PrivateKey, logger *lgg.Logger) *Handler {
+func NewHandler(service *service.Service, keyring *signer.Keyring, logger *lgg.Logger, cfg *config.Config) *Handler {
 	return &Handler{
-		service:    service,
-		logger:     logger,
-		privateKey: privateKey,
+		service: service,
+		logger:  logger,
+		keyring: keyring,
+		cfg:     cfg,
 	}
 }
 
+// GenerateOneTimeLink godoc
+// @Summary Generate an invitation link
+// @Description Mints a new invitation key with an admin api key or a token granting keys:create. The body is optional; ttl is a Go duration after which the key expires and max_uses is how many accounts may register with it (default 1) and plan names the plan granted to those accounts. purpose renewal mints a code that extends an existing account instead, trial mints a key granting a time-boxed trial and organization adds registered users to a concurrent seat pool.
+// @Tags Keys
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param X-On-Behalf-Of header string false "Requester an api key acts for, recorded in created_by (e.g. telegram:42)"
+// @Param payload body models.GenerateLinkPayload false "Key options"
+// @Success 200 {object} models.GenerateResponse
+// @Failure 400 {object} models.GenerateResponse
+// @Failure 401 {object} map[string]string "error: authentication required"
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Failure 500 {object} models.GenerateResponse
+// @Router /generate-url [post]
 func (h *Handler) GenerateOneTimeLink(c *gin.Context) {
-	link, err := h.service.GenerateOneTimeLink()
+	var payload models.GenerateLinkPayload
+	if err := c.ShouldBindJSON(&payload); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Println("Invalid generate payload:", err)
+		c.JSON(http.StatusBadRequest, models.GenerateResponse{Error: "invalid request"})
+		return
+	}
+
+	opts := service.LinkOptions{
+		MaxUses:      payload.MaxUses,
+		Purpose:      payload.Purpose,
+		Plan:         payload.Plan,
+		Trial:        payload.Trial,
+		Organization: payload.Organization,
+		CreatedBy:    currentPrincipal(c),
+	}
+	if payload.TTL != "" {
+		ttl, err := time.ParseDuration(payload.TTL)
+		if err != nil || ttl <= 0 {
+			c.JSON(http.StatusBadRequest, models.GenerateResponse{Error: "ttl must be a positive duration such as 72h"})
+			return
+		}
+		opts.TTL = ttl
+	}
+	if payload.MaxUses < 0 {
+		c.JSON(http.StatusBadRequest, models.GenerateResponse{Error: "max_uses must not be negative"})
+		return
+	}
+
+	link, url, err := h.service.GenerateOneTimeLink(opts)
+	if errors.Is(err, service.ErrPlanNotFound) || errors.Is(err, service.ErrRenewalNeedsPlan) || errors.Is(err, service.ErrUnknownKeyPurpose) || errors.Is(err, service.ErrTrialRenewal) || errors.Is(err, service.ErrOrganizationNotFound) {
+		c.JSON(http.StatusBadRequest, models.GenerateResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to generate url: %s", err.Error()),
+		c.JSON(http.StatusInternalServerError, models.GenerateResponse{
+			Error: fmt.Sprintf("failed to generate url: %s", err.Error()),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"url": link})
+	c.JSON(http.StatusOK, models.GenerateResponse{URL: url, MaxUses: link.MaxUses, Purpose: link.Purpose, Trial: link.Trial, ExpiresAt: link.ExpiresAt})
 }
 
 // RegisterUser godoc
 // @Summary Register a new user
-// @Description Registers a user using a one-time key. Key must not have been used before.
+// @Description Registers a user using a one-time key. Key must not be used up or expired.
 // @Tags Auth
 // @Accept  json
 // @Produce  json
 // @Param payload body models.RegisterPayload true "User registration data"
 // @Success 200 {object} map[string]string "message: user registered successfully"
-// @Failure 400 {object} map[string]string "error: invalid request or key already used"
+// @Failure 400 {object} map[string]string "error: invalid request, invalid or used key, or key expired"
 // @Router /register [post]
 func (h *Handler) RegisterUser(c *gin.Context) {
 	var payload models.RegisterPayload
@@ -70,7 +125,7 @@ func (h *Handler) RegisterUser(c *gin.Context) {
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticates a user using email and password
+// @Description Authenticates a user using email and password and returns a signed, time-limited approval bound to the hwid and the optional client challenge, together with an access and refresh token session. The signed status is APPROVED, TRIAL (with remaining_days) or LICENSE_EXPIRED. Organization members also check out a concurrent seat lease.
 // @Tags Auth
 // @Accept  json
 // @Produce  json
@@ -78,6 +133,8 @@ func (h *Handler) RegisterUser(c *gin.Context) {
 // @Success 200 {object} SignedResponse
 // @Failure 400 {object} map[string]string "error: invalid request"
 // @Failure 401 {object} map[string]string "error: invalid email or password"
+// @Failure 403 {object} SignedResponse "signed LICENSE_EXPIRED status, or error: device limit reached, account disabled or password reset required"
+// @Failure 409 {object} map[string]string "error: no seats available"
 // @Router /login [post]
 func (h *Handler) Login(c *gin.Context) {
 	var payload models.LoginPayload
@@ -87,60 +144,263 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	_, err := h.service.GetUserByEmailAndPassword(payload.Email, payload.Password)
+	if payload.Vhid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hwid is required"})
+		return
+	}
+
+	user, err := h.service.GetUserByEmailAndPassword(payload.Email, payload.Password)
 	if err != nil {
 		h.logger.Println("Login failed:", err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
 		return
 	}
+	if err := service.CheckAccount(user); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// a lapsed subscription is still answered with a signed status, so the
+	// client can trust the reason it shows; no device slot is taken for it
+	now := time.Now()
+	if service.LicenseExpired(user, now) {
+		h.respondApproval(c, http.StatusForbidden, h.newApproval(StatusLicenseExpired, user, &payload, now), nil)
+		return
+	}
+
+	// the seat is checked out first: it is the step most likely to be refused,
+	// and it is released again if anything after it fails
+	lease, err := h.service.CheckoutSeat(user, payload.Vhid)
+	if err != nil {
+		if errors.Is(err, service.ErrNoSeatsAvailable) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("CheckoutSeat error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check out a seat"})
+		return
+	}
 
-	// 2. Create signed payload
-	responsePayload := ResponsePayload{
-		Status: "APPROVED",
+	if _, err := h.service.RegisterDevice(user.ID, payload.Vhid); err != nil {
+		h.releaseSeat(lease)
+		if errors.Is(err, service.ErrDeviceLimitReached) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("RegisterDevice error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register device"})
+		return
 	}
-	toSign := struct {
-		Status string `json:"status"`
-		Vhid   string `json:"vhid"`
-	}{
-		Status: responsePayload.Status,
-		Vhid:   payload.Vhid,
+
+	status := StatusApproved
+	if user.Trial {
+		status = StatusTrial
+	}
+	claims := h.newApproval(status, user, &payload, now)
+	if lease != nil {
+		claims.LeaseID = lease.ID
+		claims.LeaseExpiresAt = lease.ExpiresAt.Unix()
 	}
 
-	dataToSign, err := json.Marshal(toSign)
+	session, err := h.startSession(user.ID)
 	if err != nil {
-		h.logger.Println("Failed to marshal payload:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to marshal payload"})
+		h.releaseSeat(lease)
+		h.logger.Println("Failed to start session:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session"})
+		return
+	}
+	h.respondApproval(c, http.StatusOK, claims, session)
+}
+
+// releaseSeat gives back a seat checked out by a login that did not complete
+func (h *Handler) releaseSeat(lease *models.Lease) {
+	if lease == nil {
+		return
+	}
+	if err := h.service.ReleaseLease(lease.ID, lease.HWID); err != nil {
+		h.logger.Println("ReleaseLease error:", err)
+	}
+}
+
+// newApproval builds the claims signed in response to a login
+func (h *Handler) newApproval(status string, user *models.User, payload *models.LoginPayload, now time.Time) *ApprovalClaims {
+	claims := &ApprovalClaims{
+		Status:    status,
+		UserID:    user.ID,
+		Vhid:      payload.Vhid,
+		IssuedAt:  now.Unix(),
+		NotAfter:  now.Add(h.cfg.Signing.ApprovalTTL).Unix(),
+		Challenge: payload.Challenge,
+	}
+	if user.Plan != nil {
+		claims.Plan = user.Plan.Name
+		claims.Features = user.Plan.Features
+		claims.Limits = user.Plan.Limits
+	}
+	if user.LicenseExpiresAt != nil {
+		claims.LicenseExpiresAt = user.LicenseExpiresAt.Unix()
+	}
+	if status == StatusTrial {
+		claims.RemainingDays = service.RemainingDays(user, now)
+	}
+	return claims
+}
+
+// respondApproval stamps the claims with a fresh nonce and id, signs them and writes the response
+func (h *Handler) respondApproval(c *gin.Context, status int, claims *ApprovalClaims, session *TokenResponse) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		h.logger.Println("Failed to generate nonce:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create signature"})
+		return
+	}
+	claims.Nonce = base64.RawURLEncoding.EncodeToString(nonce)
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		h.logger.Println("Failed to generate approval id:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create signature"})
 		return
 	}
+	claims.ID = hex.EncodeToString(id)
 
-	hash := sha256.Sum256(dataToSign)
-	signature, err := rsa.SignPKCS1v15(nil, h.privateKey, crypto.SHA256, hash[:])
+	doc, err := h.sign(approvalType, claims)
 	if err != nil {
 		h.logger.Println("Failed to sign payload:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create signature"})
 		return
 	}
 
-	sigBase64 := base64.StdEncoding.EncodeToString(signature)
+	c.JSON(status, SignedResponse{
+		Payload:       *claims,
+		SignedPayload: base64.StdEncoding.EncodeToString(doc.Payload),
+		Signature:     base64.StdEncoding.EncodeToString(doc.Signature),
+		Algorithm:     doc.Algorithm,
+		KeyID:         doc.KeyID,
+		Token:         doc.Token,
+		Session:       session,
+	})
+}
+
+// Renew godoc
+// @Summary Renew a subscription
+// @Description Redeems a renewal code for an existing account. The code's plan replaces the current one and its duration extends the license expiry.
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Param payload body models.RenewPayload true "Credentials and renewal code"
+// @Success 200 {object} map[string]string "message: subscription renewed, license_expires_at"
+// @Failure 400 {object} map[string]string "error: invalid request, invalid or used key, or key expired"
+// @Failure 401 {object} map[string]string "error: invalid email or password"
+// @Failure 403 {object} map[string]string "error: account disabled or password reset required"
+// @Router /renew [post]
+func (h *Handler) Renew(c *gin.Context) {
+	var payload models.RenewPayload
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	user, err := h.service.GetUserByEmailAndPassword(payload.Email, payload.Password)
+	if err != nil {
+		h.logger.Println("Renew login failed:", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
+		return
+	}
+	if err := service.CheckAccount(user); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 
-	// 3. Return response
-	response := SignedResponse{
-		Payload:   responsePayload,
-		Signature: sigBase64,
+	user, err = h.service.RenewUser(user.ID, payload.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidKey) || errors.Is(err, service.ErrKeyExpired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("RenewUser error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to renew subscription"})
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "subscription renewed",
+		"plan":               user.Plan.Name,
+		"license_expires_at": user.LicenseExpiresAt.UTC().Format(time.RFC3339),
+	})
 }
 
-type ResponsePayload struct {
+// JWKS godoc
+// @Summary Public signing keys
+// @Description Publishes every signing key as a JSON Web Key Set. Clients pick the key whose kid matches the signed response.
+// @Tags Keys
+// @Produce  json
+// @Success 200 {object} signer.JWKSet
+// @Failure 500 {object} map[string]string "error: failed to build key set"
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) JWKS(c *gin.Context) {
+	set, err := h.keyring.JWKS()
+	if err != nil {
+		h.logger.Println("Failed to build JWKS:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build key set"})
+		return
+	}
+
+	c.JSON(http.StatusOK, set)
+}
+
+// approvalType is the JWS typ of login approvals
+const approvalType = "approval+jws"
+
+// Approval statuses
+const (
+	StatusApproved       = "APPROVED"
+	StatusTrial          = "TRIAL"
+	StatusLicenseExpired = "LICENSE_EXPIRED"
+)
+
+// ApprovalClaims is the exact content covered by the login signature
+type ApprovalClaims struct {
+	// ID is unique per approval and names it in the revocation list
+	ID     string `json:"jti"`
 	Status string `json:"status"`
+	UserID uint   `json:"sub"`
+	Vhid   string `json:"vhid"`
+	// IssuedAt and NotAfter are unix seconds; clients must reject the approval outside that window
+	IssuedAt int64 `json:"iat"`
+	NotAfter int64 `json:"exp"`
+	// Nonce is unique per approval so clients can refuse one they have already seen
+	Nonce string `json:"nonce"`
+	// Challenge echoes the value supplied by the client in the login request
+	Challenge string `json:"challenge,omitempty"`
+	// Plan, Features and Limits describe what the user is entitled to
+	Plan     string         `json:"plan,omitempty" example:"pro"`
+	Features []string       `json:"features"`
+	Limits   map[string]int `json:"limits,omitempty"`
+	// LicenseExpiresAt is when the subscription ends, unix seconds; absent when it never does
+	LicenseExpiresAt int64 `json:"license_exp,omitempty"`
+	// RemainingDays counts down the trial and is only set with the TRIAL status
+	RemainingDays int `json:"remaining_days,omitempty" example:"7"`
+	// LeaseID and LeaseExpiresAt are set for organization seats; keep the lease alive with /lease/heartbeat
+	LeaseID        string `json:"lease_id,omitempty"`
+	LeaseExpiresAt int64  `json:"lease_exp,omitempty"`
 }
 
 type Request struct {
 	Vhid string `json:"hwid"`
 }
 
+// SignedResponse carries the approval in two verifiable forms. Signature covers the
+// raw bytes of SignedPayload; Token is a compact JWS that is self-contained.
+// Payload is informational and must not be re-marshalled for verification
 type SignedResponse struct {
-	Payload   ResponsePayload `json:"payload"`
-	Signature string          `json:"signature"`
+	Payload ApprovalClaims `json:"payload"`
+	// SignedPayload is the base64 of the exact ApprovalClaims bytes the signature covers
+	SignedPayload string `json:"signed_payload"`
+	Signature     string `json:"signature"`
+	Algorithm     string `json:"alg" example:"RS256"`
+	KeyID         string `json:"kid" example:"2025-01"`
+	Token         string `json:"token"`
+	// Session is only issued with an approval; it is not covered by the signature
+	Session *TokenResponse `json:"session,omitempty"`
 }
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ruziba3vich/tokenizer/internal/service"
+)
+
+// AdminListKeys godoc
+// @Summary List invitation keys
+// @Description Lists invitation keys, newest first. status is unused (still redeemable), used, expired or revoked; from and to bound the creation time as RFC 3339 or YYYY-MM-DD. Requires keys:read.
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param status query string false "unused, used, expired or revoked"
+// @Param purpose query string false "register or renewal"
+// @Param created_by query string false "Creator principal, e.g. api-key:telegram-bot or user:1"
+// @Param from query string false "Created at or after"
+// @Param to query string false "Created at or before"
+// @Param page query int false "Page, from 1"
+// @Param page_size query int false "Keys per page, at most 200"
+// @Success 200 {object} models.KeyList
+// @Failure 400 {object} map[string]string "error: invalid filter"
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Router /admin/keys [get]
+func (h *Handler) AdminListKeys(c *gin.Context) {
+	filter := service.KeyFilter{
+		Status:    c.Query("status"),
+		Purpose:   c.Query("purpose"),
+		CreatedBy: c.Query("created_by"),
+	}
+
+	var err error
+	if filter.From, err = timeQuery(c, "from", false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.To, err = timeQuery(c, "to", true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Page, filter.PageSize, err = pageQuery(c); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.service.ListKeys(filter)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownKeyStatus) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("ListKeys error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// AdminGetKey godoc
+// @Summary Get an invitation key
+// @Description Returns one invitation key by its value. Requires keys:read.
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param key path string true "Key"
+// @Success 200 {object} models.OneTimeLink
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Failure 404 {object} map[string]string "error: key not found"
+// @Router /admin/keys/{key} [get]
+func (h *Handler) AdminGetKey(c *gin.Context) {
+	link, err := h.service.GetKey(c.Param("key"))
+	if err != nil {
+		h.respondKeyError(c, "GetKey", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// AdminRevokeKey godoc
+// @Summary Revoke an invitation key
+// @Description Withdraws a key that can still be redeemed. Accounts already registered with it keep working. Requires keys:revoke.
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param key path string true "Key"
+// @Success 200 {object} models.OneTimeLink
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Failure 404 {object} map[string]string "error: key not found"
+// @Failure 409 {object} map[string]string "error: key is already used up or revoked"
+// @Router /admin/keys/{key} [delete]
+func (h *Handler) AdminRevokeKey(c *gin.Context) {
+	link, err := h.service.RevokeKey(c.Param("key"))
+	if err != nil {
+		if errors.Is(err, service.ErrKeyUsedUp) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.respondKeyError(c, "RevokeKey", err)
+		return
+	}
+
+	h.logger.Println("Key", link.ID, "revoked by", currentPrincipal(c))
+	c.JSON(http.StatusOK, link)
+}
+
+// AdminKeyRedemptions godoc
+// @Summary List who redeemed a key
+// @Description Lists the users who registered or renewed with a key, in redemption order. Requires keys:read.
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param key path string true "Key"
+// @Success 200 {array} models.KeyRedeemer
+// @Failure 403 {object} map[string]string "error: permission denied"
+// @Failure 404 {object} map[string]string "error: key not found"
+// @Router /admin/keys/{key}/redemptions [get]
+func (h *Handler) AdminKeyRedemptions(c *gin.Context) {
+	link, err := h.service.GetKey(c.Param("key"))
+	if err != nil {
+		h.respondKeyError(c, "GetKey", err)
+		return
+	}
+
+	redeemers, err := h.service.KeyRedeemers(link.ID)
+	if err != nil {
+		h.respondKeyError(c, "KeyRedeemers", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, redeemers)
+}
+
+// respondKeyError answers 404 for a missing key and logs anything else as an internal error
+func (h *Handler) respondKeyError(c *gin.Context, op string, err error) {
+	if errors.Is(err, service.ErrKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Println(op, "error:", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process key"})
+}
+
+// timeQuery parses an optional RFC 3339 or YYYY-MM-DD query parameter. A bare
+// date used as an upper bound covers the whole day
+func timeQuery(c *gin.Context, name string, endOfDay bool) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return nil, errors.New(name + " must be an RFC 3339 time or a YYYY-MM-DD date")
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// pageQuery parses the optional page and page_size query parameters
+func pageQuery(c *gin.Context) (int, int, error) {
+	var page, size int
+	var err error
+	if raw := c.Query("page"); raw != "" {
+		if page, err = strconv.Atoi(raw); err != nil || page < 1 {
+			return 0, 0, errors.New("page must be a positive integer")
+		}
+	}
+	if raw := c.Query("page_size"); raw != "" {
+		if size, err = strconv.Atoi(raw); err != nil || size < 1 {
+			return 0, 0, errors.New("page_size must be a positive integer")
+		}
+	}
+	return page, size, nil
+}
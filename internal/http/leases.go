@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/service"
+)
+
+// leaseType is the JWS typ of seat leases
+const leaseType = "lease+jws"
+
+// LeaseClaims is the signed content of a renewed seat lease
+type LeaseClaims struct {
+	LeaseID        string `json:"lease_id"`
+	UserID         uint   `json:"sub"`
+	OrganizationID uint   `json:"org"`
+	Vhid           string `json:"vhid"`
+	IssuedAt       int64  `json:"iat"`
+	NotAfter       int64  `json:"exp"`
+}
+
+// SignedLease carries a lease and the compact JWS proving it
+type SignedLease struct {
+	Payload LeaseClaims `json:"payload"`
+	Token   string      `json:"token"`
+}
+
+// LeaseHeartbeat godoc
+// @Summary Renew a seat lease
+// @Description Extends the concurrent seat checked out by /login. Clients call it well before lease_exp; a lapsed lease frees the seat and requires a new login.
+// @Tags Leases
+// @Accept  json
+// @Produce  json
+// @Param payload body models.LeasePayload true "Lease to renew"
+// @Success 200 {object} SignedLease
+// @Failure 400 {object} map[string]string "error: invalid request"
+// @Failure 403 {object} map[string]string "error: license expired, account disabled or password reset required"
+// @Failure 404 {object} map[string]string "error: lease not found"
+// @Failure 410 {object} map[string]string "error: lease expired"
+// @Router /lease/heartbeat [post]
+func (h *Handler) LeaseHeartbeat(c *gin.Context) {
+	var payload models.LeasePayload
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.LeaseID == "" || payload.Vhid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	lease, err := h.service.RenewLease(payload.LeaseID, payload.Vhid)
+	switch {
+	case errors.Is(err, service.ErrLeaseNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrLeaseExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrLicenseExpired), errors.Is(err, service.ErrUserDisabled), errors.Is(err, service.ErrPasswordResetRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		h.logger.Println("RenewLease error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to renew lease"})
+		return
+	}
+
+	claims := LeaseClaims{
+		LeaseID:        lease.ID,
+		UserID:         lease.UserID,
+		OrganizationID: lease.OrganizationID,
+		Vhid:           lease.HWID,
+		IssuedAt:       time.Now().Unix(),
+		NotAfter:       lease.ExpiresAt.Unix(),
+	}
+	doc, err := h.sign(leaseType, claims)
+	if err != nil {
+		h.logger.Println("Failed to sign lease:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create signature"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SignedLease{Payload: claims, Token: doc.Token})
+}
+
+// ReleaseLease godoc
+// @Summary Release a seat lease
+// @Description Gives a concurrent seat back immediately, e.g. when the application exits.
+// @Tags Leases
+// @Accept  json
+// @Produce  json
+// @Param payload body models.LeasePayload true "Lease to release"
+// @Success 200 {object} map[string]string "message: lease released"
+// @Failure 400 {object} map[string]string "error: invalid request"
+// @Failure 404 {object} map[string]string "error: lease not found"
+// @Router /lease/release [post]
+func (h *Handler) ReleaseLease(c *gin.Context) {
+	var payload models.LeasePayload
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.LeaseID == "" || payload.Vhid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.ReleaseLease(payload.LeaseID, payload.Vhid); err != nil {
+		if errors.Is(err, service.ErrLeaseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("ReleaseLease error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to release lease"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "lease released"})
+}
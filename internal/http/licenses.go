@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ruziba3vich/tokenizer/internal/license"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/service"
+)
+
+// IssueLicense godoc
+// @Summary Issue an offline license
+// @Description Produces a signed license file for an air-gapped machine. It can be verified offline with the public keys from /.well-known/jwks.json; see docs/license-format.md.
+// @Tags Licenses
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param payload body models.LicensePayload true "Machine to license"
+// @Success 200 {object} license.File
+// @Failure 400 {object} map[string]string "error: invalid request"
+// @Failure 401 {object} map[string]string "error: authentication required"
+// @Failure 403 {object} map[string]string "error: device limit reached or license expired"
+// @Router /licenses [post]
+func (h *Handler) IssueLicense(c *gin.Context) {
+	var payload models.LicensePayload
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.Vhid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	user := currentUser(c)
+	entitlements := h.cfg.Licenses.Entitlements
+	if user.Plan != nil {
+		entitlements = user.Plan.Features
+	}
+
+	claims, err := h.service.IssueLicense(user, payload.Vhid, entitlements, h.cfg.Licenses.Validity)
+	if err != nil {
+		if errors.Is(err, service.ErrDeviceLimitReached) || errors.Is(err, service.ErrLicenseExpired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("IssueLicense error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue license"})
+		return
+	}
+
+	file, err := license.Sign(h.keyring, *claims)
+	if err != nil {
+		h.logger.Println("Failed to sign license:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create signature"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="license.json"`)
+	c.JSON(http.StatusOK, file)
+}
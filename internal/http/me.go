@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/service"
+)
+
+// GetMe godoc
+// @Summary Get my account
+// @Description Returns the authenticated account with its plan
+// @Tags Account
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} models.User
+// @Failure 401 {object} map[string]string "error: authentication required"
+// @Router /me [get]
+func (h *Handler) GetMe(c *gin.Context) {
+	c.JSON(http.StatusOK, currentUser(c))
+}
+
+// UpdateMe godoc
+// @Summary Update my account
+// @Description Changes the names, phone or username of the authenticated account. Omitted fields are kept.
+// @Tags Account
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param payload body models.UpdateUserPayload true "Fields to change"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string "error: invalid request"
+// @Failure 401 {object} map[string]string "error: authentication required"
+// @Failure 409 {object} map[string]string "error: username already taken"
+// @Router /me [patch]
+func (h *Handler) UpdateMe(c *gin.Context) {
+	var payload models.UpdateUserPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if payload.Username != nil && strings.TrimSpace(*payload.Username) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username must not be empty"})
+		return
+	}
+
+	user, err := h.service.UpdateUser(currentUser(c).ID, &payload)
+	if err != nil {
+		if errors.Is(err, service.ErrUsernameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("UpdateUser error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteMe godoc
+// @Summary Delete my account
+// @Description Deletes the authenticated account with its devices and sessions. The password is asked again to confirm.
+// @Tags Account
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param payload body models.DeleteUserPayload true "Password confirmation"
+// @Success 200 {object} map[string]string "message: account deleted"
+// @Failure 400 {object} map[string]string "error: invalid request"
+// @Failure 401 {object} map[string]string "error: authentication required"
+// @Failure 403 {object} map[string]string "error: invalid password"
+// @Router /me [delete]
+func (h *Handler) DeleteMe(c *gin.Context) {
+	var payload models.DeleteUserPayload
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	user := currentUser(c)
+	if _, err := h.service.GetUserByEmailAndPassword(user.Email, payload.Password); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid password"})
+		return
+	}
+
+	if err := h.service.DeleteUser(user.ID); err != nil {
+		h.logger.Println("DeleteUser error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted"})
+}
+
+// ResetPassword godoc
+// @Summary Reset a password
+// @Description Sets a new password with the reset code support handed out after forcing a reset. Logins work again afterwards.
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Param payload body models.PasswordResetPayload true "Email, reset code and new password"
+// @Success 200 {object} map[string]string "message: password changed"
+// @Failure 400 {object} map[string]string "error: invalid request, or invalid or expired reset code"
+// @Router /password/reset [post]
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var payload models.PasswordResetPayload
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.Email == "" || payload.Code == "" || payload.NewPassword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.ResetPassword(payload.Email, payload.Code, payload.NewPassword); err != nil {
+		if errors.Is(err, service.ErrInvalidResetCode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("ResetPassword error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed"})
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ruziba3vich/tokenizer/internal/config"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/service"
+)
+
+const (
+	userContextKey      = "user"
+	principalContextKey = "principal"
+)
+
+// apiKeyHeader carries the admin api key of service principals
+const apiKeyHeader = "X-API-Key"
+
+// onBehalfOfHeader names the person a service principal acts for, e.g. the
+// telegram user that asked the bot for a key. It only refines the audit trail
+const onBehalfOfHeader = "X-On-Behalf-Of"
+
+// maxOnBehalfOfLength bounds what a service principal may record as its requester
+const maxOnBehalfOfLength = 64
+
+// BearerAuth authenticates the request with an access token issued by /login
+// or /token/refresh and stores the user in the context. Tokens of a session
+// ended by /logout are refused even before they expire
+func (h *Handler) BearerAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := h.authenticateBearer(c)
+		if !ok {
+			return
+		}
+
+		c.Set(userContextKey, user)
+		c.Next()
+	}
+}
+
+// authenticateBearer loads the user of the request's access token. On failure
+// it aborts the request with the matching response and returns false
+func (h *Handler) authenticateBearer(c *gin.Context) (*models.User, bool) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		c.Header("WWW-Authenticate", `Bearer realm="tokenizer"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return nil, false
+	}
+
+	claims, err := h.parseAccessToken(token)
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer realm="tokenizer", error="invalid_token"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired access token"})
+		return nil, false
+	}
+
+	active, err := h.service.SessionActive(claims.SessionID)
+	if err != nil {
+		h.logger.Println("SessionActive error:", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to authenticate"})
+		return nil, false
+	}
+	if !active {
+		c.Header("WWW-Authenticate", `Bearer realm="tokenizer", error="invalid_token"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session ended"})
+		return nil, false
+	}
+
+	user, err := h.service.GetUserByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session ended"})
+			return nil, false
+		}
+		h.logger.Println("GetUserByID error:", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to authenticate"})
+		return nil, false
+	}
+	if err := service.CheckAccount(user); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return user, true
+}
+
+// lookupAPIKey returns the admin api key equal to key. Digests are compared
+// in constant time so the comparison leaks neither content nor length
+func (h *Handler) lookupAPIKey(key string) (config.APIKeyConfig, bool) {
+	digest := sha256.Sum256([]byte(key))
+	var match config.APIKeyConfig
+	found := 0
+	for _, candidate := range h.cfg.Admin.APIKeys {
+		want := sha256.Sum256([]byte(candidate.Key))
+		if subtle.ConstantTimeCompare(digest[:], want[:]) == 1 {
+			match, found = candidate, 1
+		}
+	}
+	return match, found == 1
+}
+
+// currentPrincipal returns the principal stored by Authorize
+func currentPrincipal(c *gin.Context) string {
+	return c.GetString(principalContextKey)
+}
+
+// currentUser returns the user stored by an authentication middleware
+func currentUser(c *gin.Context) *models.User {
+	return c.MustGet(userContextKey).(*models.User)
+}
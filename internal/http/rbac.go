@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+)
+
+// rolePermissions lists what each role may do. Support staff handle everyday
+// requests; deleting accounts, revoking keys and changing roles stay with admins
+var rolePermissions = map[string][]models.Permission{
+	models.RoleUser: nil,
+	models.RoleSupport: {
+		models.PermKeysCreate, models.PermKeysRead,
+		models.PermUsersRead, models.PermUsersManage,
+		models.PermApprovalsRevoke, models.PermDevicesManage,
+	},
+	models.RoleAdmin: {
+		models.PermKeysCreate, models.PermKeysRead, models.PermKeysRevoke,
+		models.PermUsersRead, models.PermUsersManage, models.PermUsersDelete,
+		models.PermApprovalsRevoke, models.PermDevicesManage, models.PermRolesManage,
+	},
+}
+
+// Can reports whether a role grants perm
+func Can(role string, perm models.Permission) bool {
+	return slices.Contains(rolePermissions[role], perm)
+}
+
+// Authorize admits service principals whose admin api key lists perm, and users
+// whose role grants perm. The principal is stored in the context for auditing,
+// and the user too when the request carries a bearer token. A service principal
+// acting for someone names them in X-On-Behalf-Of, e.g. api-key:telegram-bot/telegram:42
+func (h *Handler) Authorize(perm models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := c.GetHeader(apiKeyHeader); key != "" {
+			apiKey, ok := h.lookupAPIKey(key)
+			if !ok {
+				h.logger.Println("Rejected an unknown admin api key from", c.ClientIP())
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+				return
+			}
+			if !slices.Contains(apiKey.Permissions, perm) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied", "permission": perm})
+				return
+			}
+			principal := "api-key:" + apiKey.Name
+			if requester := strings.TrimSpace(c.GetHeader(onBehalfOfHeader)); requester != "" {
+				if len(requester) > maxOnBehalfOfLength {
+					c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid " + onBehalfOfHeader + " header"})
+					return
+				}
+				principal += "/" + requester
+			}
+			c.Set(principalContextKey, principal)
+			c.Next()
+			return
+		}
+
+		user, ok := h.authenticateBearer(c)
+		if !ok {
+			return
+		}
+		if !Can(user.Role, perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied", "permission": perm})
+			return
+		}
+
+		c.Set(userContextKey, user)
+		c.Set(principalContextKey, "user:"+strconv.FormatUint(uint64(user.ID), 10))
+		c.Next()
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// revocationListType is the JWS typ of the signed revocation list
+const revocationListType = "crl+jws"
+
+// RevocationEntry revokes the approval with ApprovalID, or every approval of
+// UserID (narrowed to Vhid when set) issued at or before RevokedAt
+type RevocationEntry struct {
+	ApprovalID string `json:"jti,omitempty"`
+	UserID     uint   `json:"sub,omitempty"`
+	Vhid       string `json:"vhid,omitempty"`
+	RevokedAt  int64  `json:"revoked_at"`
+}
+
+// RevocationList is the signed content of /revocations. It only lists revocations
+// that can still affect an unexpired approval or offline license
+type RevocationList struct {
+	IssuedAt int64 `json:"iat"`
+	// NextUpdate is when a new list is published; clients may cache this one until then
+	NextUpdate int64             `json:"exp"`
+	Entries    []RevocationEntry `json:"revoked"`
+}
+
+// SignedRevocationList carries the list and the compact JWS proving it
+type SignedRevocationList struct {
+	Payload RevocationList `json:"payload"`
+	Token   string         `json:"token"`
+}
+
+// revocationCache holds the last signed list until its NextUpdate
+type revocationCache struct {
+	mu   sync.Mutex
+	list *SignedRevocationList
+}
+
+// RevocationList godoc
+// @Summary Signed revocation list
+// @Description Lists approvals and offline licenses revoked before their expiry, signed like the approvals themselves. The list is regenerated periodically; cache it until exp and reject approvals matching an entry by jti, or by sub (and vhid) with iat not after revoked_at.
+// @Tags Keys
+// @Produce  json
+// @Success 200 {object} SignedRevocationList
+// @Failure 500 {object} map[string]string "error: failed to build revocation list"
+// @Router /revocations [get]
+func (h *Handler) RevocationList(c *gin.Context) {
+	list, err := h.revocationList(time.Now())
+	if err != nil {
+		h.logger.Println("Failed to build revocation list:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build revocation list"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age="+maxAge(list.Payload.NextUpdate, time.Now()))
+	c.JSON(http.StatusOK, list)
+}
+
+// revocationList returns the cached list, signing a new one once the cached one is due
+func (h *Handler) revocationList(now time.Time) (*SignedRevocationList, error) {
+	h.revocations.mu.Lock()
+	defer h.revocations.mu.Unlock()
+
+	if cached := h.revocations.list; cached != nil && now.Unix() < cached.Payload.NextUpdate {
+		return cached, nil
+	}
+
+	// approvals and licenses issued before the longer of their lifetimes have expired
+	// by themselves, so older revocations can be left out
+	window := max(h.cfg.Signing.ApprovalTTL, h.cfg.Licenses.Validity)
+	revocations, err := h.service.ListRevocations(now.Add(-window))
+	if err != nil {
+		return nil, err
+	}
+
+	list := RevocationList{
+		IssuedAt:   now.Unix(),
+		NextUpdate: now.Add(h.cfg.Revocations.ListInterval).Unix(),
+		Entries:    make([]RevocationEntry, 0, len(revocations)),
+	}
+	for _, r := range revocations {
+		entry := RevocationEntry{ApprovalID: r.ApprovalID, Vhid: r.HWID, RevokedAt: r.CreatedAt.Unix()}
+		if r.UserID != nil {
+			entry.UserID = *r.UserID
+		}
+		list.Entries = append(list.Entries, entry)
+	}
+
+	doc, err := h.sign(revocationListType, list)
+	if err != nil {
+		return nil, err
+	}
+	h.revocations.list = &SignedRevocationList{Payload: list, Token: doc.Token}
+	return h.revocations.list, nil
+}
+
+// maxAge returns the seconds left until the unix time exp, as a Cache-Control value
+func maxAge(exp int64, now time.Time) string {
+	return strconv.FormatInt(max(exp-now.Unix(), 0), 10)
+}
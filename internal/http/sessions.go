@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/pkg/signer"
+	"github.com/ruziba3vich/tokenizer/internal/service"
+)
+
+// accessTokenType is the JWS typ of access tokens (RFC 9068)
+const accessTokenType = "at+jwt"
+
+var errAccessTokenExpired = errors.New("access token expired")
+
+// AccessClaims is the content of an access token
+type AccessClaims struct {
+	ID     string `json:"jti"`
+	UserID uint   `json:"sub"`
+	// SessionID is the refresh token family the access token was issued for
+	SessionID string `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	NotAfter  int64  `json:"exp"`
+}
+
+// TokenResponse is the session issued by /login and /token/refresh
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	// ExpiresIn is the lifetime of the access token in seconds
+	ExpiresIn    int64  `json:"expires_in" example:"900"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken godoc
+// @Summary Refresh a session
+// @Description Exchanges a refresh token for a new access token and a new refresh token. Each refresh token works once; presenting a used one again revokes the whole session.
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Param payload body models.RefreshPayload true "Refresh token"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} map[string]string "error: invalid request"
+// @Failure 401 {object} map[string]string "error: invalid, expired or reused refresh token"
+// @Router /token/refresh [post]
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var payload models.RefreshPayload
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	raw, token, err := h.service.RotateRefreshToken(payload.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenExpired) || errors.Is(err, service.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("RotateRefreshToken error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh session"})
+		return
+	}
+
+	session, err := h.newSession(raw, token.UserID, token.FamilyID)
+	if err != nil {
+		h.logger.Println("Failed to sign access token:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Revokes the session of the refresh token. Access tokens already issued for it stop working as well.
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Param payload body models.RefreshPayload true "Refresh token"
+// @Success 200 {object} map[string]string "message: logged out"
+// @Failure 400 {object} map[string]string "error: invalid request"
+// @Failure 401 {object} map[string]string "error: invalid refresh token"
+// @Router /logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	var payload models.RefreshPayload
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.service.RevokeSession(payload.RefreshToken); err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Println("RevokeSession error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// startSession opens a refresh token family for the user and returns its first token pair
+func (h *Handler) startSession(userID uint) (*TokenResponse, error) {
+	raw, token, err := h.service.CreateSession(userID)
+	if err != nil {
+		return nil, err
+	}
+	return h.newSession(raw, userID, token.FamilyID)
+}
+
+// newSession pairs a refresh token with a freshly signed access token
+func (h *Handler) newSession(refreshToken string, userID uint, sessionID string) (*TokenResponse, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	claims := AccessClaims{
+		ID:        hex.EncodeToString(id),
+		UserID:    userID,
+		SessionID: sessionID,
+		IssuedAt:  now.Unix(),
+		NotAfter:  now.Add(h.cfg.Sessions.AccessTTL).Unix(),
+	}
+	doc, err := h.sign(accessTokenType, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken:  doc.Token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(h.cfg.Sessions.AccessTTL / time.Second),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// parseAccessToken verifies an access token and returns its claims while it is unexpired
+func (h *Handler) parseAccessToken(token string) (*AccessClaims, error) {
+	header, data, err := h.keyring.VerifyCompact(token)
+	if err != nil {
+		return nil, err
+	}
+	if header.Typ != accessTokenType {
+		return nil, signer.ErrMalformedToken
+	}
+
+	var claims AccessClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, signer.ErrMalformedToken
+	}
+	if time.Now().Unix() >= claims.NotAfter {
+		return nil, errAccessTokenExpired
+	}
+	return &claims, nil
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"encoding/json"
+
+	"github.com/ruziba3vich/tokenizer/internal/pkg/signer"
+)
+
+// signedDocument carries one payload in both the detached and the compact JWS form
+type signedDocument struct {
+	Payload   []byte
+	Signature []byte
+	Token     string
+	Algorithm string
+	KeyID     string
+}
+
+// sign marshals v once and signs those exact bytes with the active key
+func (h *Handler) sign(typ string, v any) (*signedDocument, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, key := h.keyring.Active()
+	signature, err := key.Sign(payload)
+	if err != nil {
+		return nil, err
+	}
+	token, err := signer.SignCompact(kid, key, typ, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signedDocument{
+		Payload:   payload,
+		Signature: signature,
+		Token:     token,
+		Algorithm: key.Algorithm(),
+		KeyID:     kid,
+	}, nil
+}
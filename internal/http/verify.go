@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/pkg/signer"
+	"github.com/ruziba3vich/tokenizer/internal/service"
+)
+
+// Verdict reasons returned by /verify
+const (
+	ReasonMalformed        = "malformed"
+	ReasonInvalidSignature = "invalid_signature"
+	ReasonExpired          = "expired"
+	ReasonNotApproved      = "not_approved"
+	ReasonUserInactive     = "user_inactive"
+	ReasonUserDisabled     = "user_disabled"
+	ReasonPasswordReset    = "password_reset_required"
+	ReasonDeviceInactive   = "device_inactive"
+	ReasonRevoked          = "revoked"
+)
+
+// Verdict is the outcome of checking an approval
+type Verdict struct {
+	Valid bool `json:"valid"`
+	// Reason is empty for a valid approval
+	Reason string          `json:"reason,omitempty" example:"expired"`
+	Claims *ApprovalClaims `json:"claims,omitempty"`
+	KeyID  string          `json:"kid,omitempty"`
+}
+
+// VerifyApproval godoc
+// @Summary Verify a login approval
+// @Description Checks an approval produced by /login on behalf of a third party: signature, expiry, revocation, and that the user and device are still active; a disabled account or one awaiting a password reset fails with user_disabled or password_reset_required. Send either token or signed_payload, signature and kid.
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Param payload body models.VerifyPayload true "Approval to check"
+// @Success 200 {object} Verdict
+// @Failure 400 {object} map[string]string "error: invalid request"
+// @Router /verify [post]
+func (h *Handler) VerifyApproval(c *gin.Context) {
+	var payload models.VerifyPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var (
+		kid  string
+		data []byte
+		err  error
+	)
+	switch {
+	case payload.Token != "":
+		kid, data, err = h.verifyToken(payload.Token)
+	case payload.SignedPayload != "" && payload.Signature != "":
+		kid, data, err = h.verifyDetached(payload)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token or signed_payload and signature are required"})
+		return
+	}
+	if err != nil {
+		reason := ReasonInvalidSignature
+		if errors.Is(err, signer.ErrMalformedToken) {
+			reason = ReasonMalformed
+		}
+		c.JSON(http.StatusOK, Verdict{Reason: reason, KeyID: kid})
+		return
+	}
+
+	var claims ApprovalClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		c.JSON(http.StatusOK, Verdict{Reason: ReasonMalformed, KeyID: kid})
+		return
+	}
+
+	verdict := Verdict{Claims: &claims, KeyID: kid}
+	verdict.Reason, err = h.checkApproval(&claims)
+	if err != nil {
+		h.logger.Println("VerifyApproval error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify approval"})
+		return
+	}
+	verdict.Valid = verdict.Reason == ""
+
+	c.JSON(http.StatusOK, verdict)
+}
+
+func (h *Handler) verifyToken(token string) (string, []byte, error) {
+	header, data, err := h.keyring.VerifyCompact(token)
+	if err != nil {
+		return "", nil, err
+	}
+	if header.Typ != approvalType {
+		return header.Kid, nil, signer.ErrMalformedToken
+	}
+	return header.Kid, data, nil
+}
+
+func (h *Handler) verifyDetached(payload models.VerifyPayload) (string, []byte, error) {
+	data, err := base64.StdEncoding.DecodeString(payload.SignedPayload)
+	if err != nil {
+		return payload.KeyID, nil, signer.ErrMalformedToken
+	}
+	signature, err := base64.StdEncoding.DecodeString(payload.Signature)
+	if err != nil {
+		return payload.KeyID, nil, signer.ErrMalformedToken
+	}
+
+	key, ok := h.keyring.Lookup(payload.KeyID)
+	if !ok {
+		return payload.KeyID, nil, signer.ErrUnknownKey
+	}
+	if err := signer.Verify(key.Algorithm(), key.Public(), data, signature); err != nil {
+		return payload.KeyID, nil, err
+	}
+	return payload.KeyID, data, nil
+}
+
+// checkApproval returns the reason a correctly signed approval is no longer valid, or an empty string
+func (h *Handler) checkApproval(claims *ApprovalClaims) (string, error) {
+	if time.Now().Unix() >= claims.NotAfter {
+		return ReasonExpired, nil
+	}
+	if claims.Status != StatusApproved && claims.Status != StatusTrial {
+		return ReasonNotApproved, nil
+	}
+
+	revoked, err := h.service.ApprovalRevoked(claims.ID, claims.UserID, claims.Vhid, time.Unix(claims.IssuedAt, 0))
+	if err != nil {
+		return "", err
+	}
+	if revoked {
+		return ReasonRevoked, nil
+	}
+
+	// the account is held to the same standard as a fresh login
+	user, err := h.service.GetUserByID(claims.UserID)
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return ReasonUserInactive, nil
+	case err != nil:
+		return "", err
+	}
+	switch err := service.CheckAccount(user); {
+	case errors.Is(err, service.ErrUserDisabled):
+		return ReasonUserDisabled, nil
+	case errors.Is(err, service.ErrPasswordResetRequired):
+		return ReasonPasswordReset, nil
+	}
+
+	device, err := h.service.GetDevice(claims.UserID, claims.Vhid)
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return ReasonUserInactive, nil
+	case errors.Is(err, service.ErrDeviceNotFound):
+		return ReasonDeviceInactive, nil
+	case err != nil:
+		return "", err
+	}
+	if !device.Active {
+		return ReasonDeviceInactive, nil
+	}
+	return "", nil
+}
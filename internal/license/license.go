@@ -0,0 +1,85 @@
+package license
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/pkg/signer"
+)
+
+const (
+	// Format identifies the layout of a license file
+	Format = "tokenizer-license/v1"
+	// Type is the JWS typ of the license token
+	Type = "license+jws"
+)
+
+var (
+	ErrWrongFormat    = errors.New("unsupported license format")
+	ErrWrongType      = errors.New("token is not a license")
+	ErrNotYetValid    = errors.New("license is not valid yet")
+	ErrLicenseExpired = errors.New("license expired")
+)
+
+// Claims is the signed content of an offline license
+type Claims struct {
+	ID           string   `json:"jti"`
+	UserID       uint     `json:"sub"`
+	Email        string   `json:"email"`
+	HWID         string   `json:"hwid"`
+	Entitlements []string `json:"entitlements"`
+	IssuedAt     int64    `json:"iat"`
+	NotBefore    int64    `json:"nbf"`
+	NotAfter     int64    `json:"exp"`
+}
+
+// File is the document handed to the user. Claims is a readable copy;
+// only the content of Token is authoritative
+type File struct {
+	Format string `json:"format"`
+	Claims Claims `json:"claims"`
+	Token  string `json:"token"`
+}
+
+// Sign produces a license file signed by the active key of the ring
+func Sign(ring *signer.Keyring, claims Claims) (*File, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, key := ring.Active()
+	token, err := signer.SignCompact(kid, key, Type, payload)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Format: Format, Claims: claims, Token: token}, nil
+}
+
+// Verify checks the signature and validity window of a license file and returns its signed claims
+func Verify(keys signer.CompactVerifier, file *File, now time.Time) (*Claims, error) {
+	if file.Format != Format {
+		return nil, ErrWrongFormat
+	}
+
+	header, payload, err := keys.VerifyCompact(file.Token)
+	if err != nil {
+		return nil, err
+	}
+	if header.Typ != Type {
+		return nil, ErrWrongType
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, signer.ErrMalformedToken
+	}
+	if now.Unix() < claims.NotBefore {
+		return nil, ErrNotYetValid
+	}
+	if now.Unix() >= claims.NotAfter {
+		return nil, ErrLicenseExpired
+	}
+	return &claims, nil
+}
@@ -1,9 +1,192 @@
 package models
 
+import "time"
+
+// User roles, from least to most privileged
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleSupport || role == RoleAdmin
+}
+
+// Permission names an action on the admin endpoints
+type Permission string
+
+// Permissions granted by roles and admin api keys
+const (
+	PermKeysCreate      Permission = "keys:create"
+	PermKeysRead        Permission = "keys:read"
+	PermKeysRevoke      Permission = "keys:revoke"
+	PermUsersRead       Permission = "users:read"
+	PermUsersManage     Permission = "users:manage"
+	PermUsersDelete     Permission = "users:delete"
+	PermApprovalsRevoke Permission = "approvals:revoke"
+	PermDevicesManage   Permission = "devices:manage"
+	PermRolesManage     Permission = "roles:manage"
+)
+
+// ValidPermission reports whether perm is one of the known permissions
+func ValidPermission(perm Permission) bool {
+	switch perm {
+	case PermKeysCreate, PermKeysRead, PermKeysRevoke,
+		PermUsersRead, PermUsersManage, PermUsersDelete,
+		PermApprovalsRevoke, PermDevicesManage, PermRolesManage:
+		return true
+	}
+	return false
+}
+
+// Invitation key purposes
+const (
+	KeyPurposeRegister = "register"
+	KeyPurposeRenewal  = "renewal"
+)
+
 type OneTimeLink struct {
-	ID   uint   `gorm:"primaryKey"`
-	Key  string `gorm:"uniqueIndex;not null"`
-	Used bool   `gorm:"default:false"`
+	ID          uint            `json:"id" example:"1" gorm:"primaryKey"`
+	Key         string          `json:"key" example:"9f86d081884c7d65" gorm:"uniqueIndex;not null"`
+	Used        bool            `json:"used" gorm:"default:false"`
+	MaxUses     int             `json:"max_uses" example:"10" gorm:"not null;default:1"`
+	UsesCount   int             `json:"uses_count" example:"3" gorm:"not null;default:0"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty" gorm:"index"`
+	Redemptions []KeyRedemption `json:"-" gorm:"foreignKey:LinkID"`
+	// Purpose tells whether the key registers a new account or renews an existing one
+	Purpose string `json:"purpose" example:"register" gorm:"not null;default:register"`
+	// Trial keys register accounts with a short trial window instead of the plan duration
+	Trial bool `json:"trial" gorm:"not null;default:false"`
+	// PlanID is the plan granted to users registering or renewing with the key
+	PlanID *uint `json:"plan_id,omitempty"`
+	Plan   *Plan `json:"plan,omitempty"`
+	// OrganizationID puts users registering with the key into an organization's seat pool
+	OrganizationID *uint `json:"organization_id,omitempty"`
+	// CreatedBy names the principal that minted the key, e.g. api-key:telegram-bot/telegram:42
+	CreatedBy string `json:"created_by" example:"api-key:telegram-bot/telegram:42" gorm:"index;not null;default:''"`
+	// RevokedAt is set when an admin withdrew the key before it was used up
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Organization groups users that share a pool of concurrent seats
+type Organization struct {
+	ID        uint      `json:"id" example:"1" gorm:"primaryKey"`
+	Name      string    `json:"name" example:"acme" gorm:"uniqueIndex;not null"`
+	Seats     int       `json:"seats" example:"5" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Lease is a seat of an organization held by one machine. It lapses at
+// ExpiresAt unless the client keeps renewing it with heartbeats
+type Lease struct {
+	ID             string     `json:"id" gorm:"primaryKey"`
+	OrganizationID uint       `json:"organization_id" gorm:"index;not null"`
+	UserID         uint       `json:"user_id" gorm:"index;not null"`
+	HWID           string     `json:"hwid" gorm:"not null"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"index;not null"`
+	ReleasedAt     *time.Time `json:"released_at,omitempty"`
+}
+
+// Revocation invalidates approvals before they expire. It names one approval by
+// ApprovalID, or every approval issued up to CreatedAt to a user or, with HWID, to one of their devices
+type Revocation struct {
+	ID         uint      `json:"id" example:"1" gorm:"primaryKey"`
+	ApprovalID string    `json:"jti,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015" gorm:"index"`
+	UserID     *uint     `json:"user_id,omitempty" gorm:"index"`
+	HWID       string    `json:"hwid,omitempty" example:"4C4C4544-0038-3010-8050-B7C04F4E3732"`
+	Reason     string    `json:"reason,omitempty" example:"laptop stolen"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// RefreshToken is one link of a session's rotation chain. Only the SHA-256 of
+// the token is stored; every refresh revokes it and adds a new one to the same family
+type RefreshToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	FamilyID  string `gorm:"index;not null"`
+	TokenHash string `gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+}
+
+// Plan is a product tier; its features and limits are embedded in signed approvals
+type Plan struct {
+	ID       uint           `json:"id" example:"1" gorm:"primaryKey"`
+	Name     string         `json:"name" example:"pro" gorm:"uniqueIndex;not null"`
+	Features []string       `json:"features" example:"export,sync" gorm:"serializer:json"`
+	Limits   map[string]int `json:"limits,omitempty" gorm:"serializer:json"`
+	// DurationDays is how long a registration or renewal grants access; 0 never expires
+	DurationDays int       `json:"duration_days" example:"30" gorm:"not null;default:0"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// KeyRedemption records a user registered through an invitation key
+type KeyRedemption struct {
+	ID        uint `gorm:"primaryKey"`
+	LinkID    uint `gorm:"index;not null"`
+	UserID    uint `gorm:"index;not null"`
+	CreatedAt time.Time
+}
+
+// UserList is one page of users
+type UserList struct {
+	Users    []User `json:"users"`
+	Total    int64  `json:"total" example:"42"`
+	Page     int    `json:"page" example:"1"`
+	PageSize int    `json:"page_size" example:"50"`
+}
+
+// KeyRedeemer is a user who registered or renewed with a key. Email and
+// Username are empty when the account has been deleted since
+type KeyRedeemer struct {
+	UserID     uint      `json:"user_id" example:"1"`
+	Email      string    `json:"email,omitempty" example:"john@example.com"`
+	Username   string    `json:"username,omitempty" example:"johndoe"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+}
+
+// KeyList is one page of invitation keys
+type KeyList struct {
+	Keys     []OneTimeLink `json:"keys"`
+	Total    int64         `json:"total" example:"42"`
+	Page     int           `json:"page" example:"1"`
+	PageSize int           `json:"page_size" example:"50"`
+}
+
+// Expired reports whether the link has an expiry that is not after now
+func (l *OneTimeLink) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// GenerateLinkPayload represents the optional input of key generation
+type GenerateLinkPayload struct {
+	TTL     string `json:"ttl,omitempty" example:"72h"`
+	MaxUses int    `json:"max_uses,omitempty" example:"10"`
+	Plan    string `json:"plan,omitempty" example:"pro"`
+	// Purpose is register (default) or renewal; renewal keys require a plan with a duration
+	Purpose string `json:"purpose,omitempty" example:"register"`
+	// Trial mints a registration key granting a time-boxed trial, on the configured trial plan unless plan is set
+	Trial bool `json:"trial,omitempty"`
+	// Organization names the seat pool users of a registration key join
+	Organization string `json:"organization,omitempty" example:"acme"`
+}
+
+// LeasePayload identifies a seat lease in heartbeat and release requests
+type LeasePayload struct {
+	LeaseID string `json:"lease_id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	Vhid    string `json:"hwid" example:"4C4C4544-0038-3010-8050-B7C04F4E3732"`
+}
+
+// RefreshPayload carries the refresh token of /token/refresh and /logout
+type RefreshPayload struct {
+	RefreshToken string `json:"refresh_token" example:"pX3Kq1mR9f0..."`
 }
 
 // RegisterPayload represents the user registration input
@@ -22,20 +205,124 @@ type LoginPayload struct {
 	Email    string `json:"email" example:"john@example.com"`
 	Password string `json:"password" example:"securepassword123"`
 	Vhid     string `json:"hwid"`
+	// Challenge is an optional client nonce echoed back inside the signed approval
+	Challenge string `json:"challenge,omitempty" example:"b1946ac92492d234"`
+}
+
+// RenewPayload represents a renewal of an existing account
+type RenewPayload struct {
+	Email    string `json:"email" example:"john@example.com"`
+	Password string `json:"password" example:"securepassword123"`
+	Code     string `json:"renewal_code" example:"abc123"`
+}
+
+// UpdateUserPayload represents a partial update of the authenticated account; omitted fields are kept
+type UpdateUserPayload struct {
+	FirstName *string `json:"first_name,omitempty" example:"John"`
+	LastName  *string `json:"last_name,omitempty" example:"Doe"`
+	Phone     *string `json:"phone,omitempty" example:"+998901234567"`
+	Username  *string `json:"username,omitempty" example:"johndoe"`
+}
+
+// DeleteUserPayload confirms the deletion of the authenticated account
+type DeleteUserPayload struct {
+	Password string `json:"password" example:"securepassword123"`
+}
+
+// RolePayload represents a role change
+type RolePayload struct {
+	Role string `json:"role" example:"support"`
+}
+
+// RevokePayload represents an admin revocation: either one approval by jti, or
+// every approval of a user, optionally narrowed to one hwid
+type RevokePayload struct {
+	ApprovalID string `json:"jti,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	UserID     uint   `json:"user_id,omitempty" example:"1"`
+	Vhid       string `json:"hwid,omitempty" example:"4C4C4544-0038-3010-8050-B7C04F4E3732"`
+	Reason     string `json:"reason,omitempty" example:"laptop stolen"`
+}
+
+// DisableUserPayload optionally explains why an account is disabled
+type DisableUserPayload struct {
+	Reason string `json:"reason,omitempty" example:"chargeback"`
+}
+
+// PasswordResetPayload sets a new password with the code handed out by support
+type PasswordResetPayload struct {
+	Email       string `json:"email" example:"john@example.com"`
+	Code        string `json:"reset_code" example:"pX3Kq1mR9f0"`
+	NewPassword string `json:"new_password" example:"anothersecurepassword"`
+}
+
+// PasswordResetResponse carries the code the user needs to set a new password
+type PasswordResetResponse struct {
+	Code      string    `json:"reset_code" example:"pX3Kq1mR9f0"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LicensePayload represents an offline license request
+type LicensePayload struct {
+	Vhid string `json:"hwid" example:"4C4C4544-0038-3010-8050-B7C04F4E3732"`
+}
+
+// VerifyPayload represents an approval to check, either as the compact token
+// or as the detached signed_payload, signature and kid of a login response
+type VerifyPayload struct {
+	Token         string `json:"token,omitempty"`
+	SignedPayload string `json:"signed_payload,omitempty"`
+	Signature     string `json:"signature,omitempty"`
+	KeyID         string `json:"kid,omitempty"`
 }
 
 // User represents the returned user
 type User struct {
-	ID        uint   `json:"id" example:"1" gorm:"primaryKey"`
-	FirstName string `json:"first_name" example:"John"`
-	LastName  string `json:"last_name" example:"Doe"`
-	Email     string `json:"email" example:"john@example.com" gorm:"uniqueIndex:idx_user_email;not null"`
-	Phone     string `json:"phone,omitempty" example:"+998901234567"`
-	Username  string `json:"username" example:"johndoe" gorm:"uniqueIndex:idx_user_username;not null"`
-	Password  string `json:"password" example:"password"`
+	ID        uint     `json:"id" example:"1" gorm:"primaryKey"`
+	FirstName string   `json:"first_name" example:"John"`
+	LastName  string   `json:"last_name" example:"Doe"`
+	Email     string   `json:"email" example:"john@example.com" gorm:"uniqueIndex:idx_user_email;not null"`
+	Phone     string   `json:"phone,omitempty" example:"+998901234567"`
+	Username  string   `json:"username" example:"johndoe" gorm:"uniqueIndex:idx_user_username;not null"`
+	Password  string   `json:"-"`
+	Devices   []Device `json:"devices,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	// DeviceReleasedAt is when the user last deactivated one of their devices
+	DeviceReleasedAt *time.Time `json:"-"`
+	DeviceReleases   int        `json:"-" gorm:"not null;default:0"`
+	PlanID           *uint      `json:"plan_id,omitempty"`
+	Plan             *Plan      `json:"plan,omitempty"`
+	// Trial is set while the account runs on a trial key and cleared by a renewal
+	Trial bool `json:"trial" gorm:"not null;default:false"`
+	// OrganizationID makes the user draw a concurrent seat from the organization on login
+	OrganizationID *uint `json:"organization_id,omitempty"`
+	// LicenseExpiresAt ends the access granted by the plan; nil never expires
+	LicenseExpiresAt *time.Time `json:"license_expires_at,omitempty"`
+	// Role decides which admin endpoints the user may call
+	Role string `json:"role" example:"user" gorm:"not null;default:user"`
+	// DisabledAt is set while support has locked the account; disabled users can not log in
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	// PasswordResetRequired blocks logins until the user sets a new password with the reset code
+	PasswordResetRequired bool       `json:"password_reset_required" gorm:"not null;default:false"`
+	PasswordResetHash     string     `json:"-"`
+	PasswordResetExpires  *time.Time `json:"-"`
+}
+
+// Device is a machine, identified by its hardware id, an account has signed in from
+type Device struct {
+	ID         uint      `json:"id" example:"1" gorm:"primaryKey"`
+	UserID     uint      `json:"-" gorm:"uniqueIndex:idx_device_user_hwid;not null"`
+	HWID       string    `json:"hwid" example:"4C4C4544-0038-3010-8050-B7C04F4E3732" gorm:"uniqueIndex:idx_device_user_hwid;not null"`
+	Active     bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// DeactivatedAt is set when the user released the device to free its slot
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
 }
 
 type GenerateResponse struct {
-	Error string `json:"error,omitempty"`
-	URL   string `json:"url,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	MaxUses   int        `json:"max_uses,omitempty"`
+	Purpose   string     `json:"purpose,omitempty"`
+	Trial     bool       `json:"trial,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
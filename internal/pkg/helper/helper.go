@@ -5,15 +5,12 @@ import (
 	"log"
 	"time"
 
+	"github.com/ruziba3vich/tokenizer/internal/config"
 	"github.com/ruziba3vich/tokenizer/internal/models"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var (
-	dsn = "host=168.119.255.188 user=vinturm_user password=vinturm_pwd dbname=vinturm_db port=5432 sslmode=disable"
-)
-
 func GenerateTimeUUID() string {
 	now := time.Now()
 	timeComponent := fmt.Sprintf("%04d%02d%02d%02d%02d%02d%09d",
@@ -34,31 +31,34 @@ func GenerateTimeUUID() string {
 		timeComponent[20:32])
 }
 
-func NewDB() (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+func NewDB(cfg *config.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.Database.DSN()), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&models.OneTimeLink{}, &models.User{})
-	if err != nil {
-		log.Fatalf("AutoMigration failed: %v", err)
+	if err := Migrate(db); err != nil {
+		log.Printf("Migration failed: %v", err)
 		return nil, err
 	}
 
-	err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email)").Error
-	if err != nil {
-		log.Printf("Failed to create email index: %v", err)
-		return nil, err
-	}
+	log.Println("Migration and indexing successful")
+	return db, nil
+}
 
-	err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username)").Error
+// Migrate creates or updates every table and index of the service
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(&models.Plan{}, &models.Organization{}, &models.Lease{}, &models.OneTimeLink{}, &models.User{}, &models.KeyRedemption{}, &models.Device{}, &models.Revocation{}, &models.RefreshToken{})
 	if err != nil {
-		log.Printf("Failed to create username index: %v", err)
-		return nil, err
+		return fmt.Errorf("automigration failed: %w", err)
 	}
 
-	log.Println("Migration and indexing successful")
-	return db, nil
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email)").Error; err != nil {
+		return fmt.Errorf("failed to create email index: %w", err)
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username)").Error; err != nil {
+		return fmt.Errorf("failed to create username index: %w", err)
+	}
+	return nil
 }
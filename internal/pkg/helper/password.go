@@ -0,0 +1,96 @@
+package helper
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argonTime    uint32 = 1
+	argonMemory  uint32 = 64 * 1024
+	argonThreads uint8  = 4
+	argonKeyLen  uint32 = 32
+	argonSaltLen        = 16
+
+	argonPrefix = "$argon2id$"
+
+	// upper bounds accepted when reading a stored hash
+	maxArgonMemory uint32 = 1024 * 1024
+	maxArgonTime   uint32 = 16
+)
+
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// HashPassword hashes a password with argon2id and returns it in the PHC string format
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	hash := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argonPrefix, argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash)), nil
+}
+
+// IsPasswordHashed reports whether a stored password is an argon2id hash rather than a legacy plaintext value
+func IsPasswordHashed(stored string) bool {
+	return strings.HasPrefix(stored, argonPrefix)
+}
+
+// ComparePassword checks a password against a stored value in constant time.
+// Legacy plaintext values are still accepted so that they can be rehashed on login
+func ComparePassword(stored, password string) (bool, error) {
+	if !IsPasswordHashed(stored) {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1, nil
+	}
+
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 {
+		return false, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrInvalidHash
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, ErrInvalidHash
+	}
+	// argon2 panics on zero rounds or threads, and a stored hash must not make a login allocate without bound
+	if time < 1 || threads < 1 || memory < 8*uint32(threads) || memory > maxArgonMemory || time > maxArgonTime {
+		return false, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrInvalidHash
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash) == 0 {
+		return false, ErrInvalidHash
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(hash)))
+	return subtle.ConstantTimeCompare(hash, candidate) == 1, nil
+}
+
+// PasswordNeedsRehash reports whether a stored password should be replaced by a fresh hash
+func PasswordNeedsRehash(stored string) bool {
+	if !IsPasswordHashed(stored) {
+		return true
+	}
+	params := fmt.Sprintf("v=%d$m=%d,t=%d,p=%d$", argon2.Version, argonMemory, argonTime, argonThreads)
+	return !strings.HasPrefix(strings.TrimPrefix(stored, argonPrefix), params)
+}
@@ -0,0 +1,95 @@
+package helper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHashPasswordRoundTrip(t *testing.T) {
+	stored, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if !IsPasswordHashed(stored) {
+		t.Fatalf("%q is not an argon2id hash", stored)
+	}
+	if PasswordNeedsRehash(stored) {
+		t.Error("a fresh hash needs a rehash")
+	}
+
+	ok, err := ComparePassword(stored, "correct horse battery staple")
+	if err != nil || !ok {
+		t.Errorf("compare with the right password = %v, %v; want true, nil", ok, err)
+	}
+	ok, err = ComparePassword(stored, "correct horse battery stapler")
+	if err != nil || ok {
+		t.Errorf("compare with a wrong password = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestHashPasswordSalts(t *testing.T) {
+	a, _ := HashPassword("same")
+	b, _ := HashPassword("same")
+	if a == b {
+		t.Error("two hashes of the same password are equal")
+	}
+}
+
+func TestComparePasswordMalformedHash(t *testing.T) {
+	valid, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	parts := strings.Split(valid, "$")
+
+	tests := map[string]string{
+		"too few parts":   "$argon2id$v=19$m=65536,t=1,p=4$c2FsdA",
+		"wrong version":   strings.Replace(valid, "v=19", "v=16", 1),
+		"bad parameters":  strings.Replace(valid, parts[3], "m=x,t=1,p=4", 1),
+		"zero rounds":     strings.Replace(valid, parts[3], "m=65536,t=0,p=4", 1),
+		"zero threads":    strings.Replace(valid, parts[3], "m=65536,t=1,p=0", 1),
+		"huge memory":     strings.Replace(valid, parts[3], "m=4294967295,t=1,p=4", 1),
+		"salt not base64": strings.Replace(valid, parts[4], "!!!", 1),
+		"hash not base64": strings.Replace(valid, parts[5], "!!!", 1),
+		"empty hash":      strings.TrimSuffix(valid, parts[5]),
+	}
+	for name, stored := range tests {
+		t.Run(name, func(t *testing.T) {
+			ok, err := ComparePassword(stored, "secret")
+			if ok || !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("compare = %v, %v; want false, %v", ok, err, ErrInvalidHash)
+			}
+		})
+	}
+}
+
+func TestComparePasswordLegacyPlaintext(t *testing.T) {
+	const stored = "hunter2"
+	if IsPasswordHashed(stored) {
+		t.Fatal("plaintext is reported as hashed")
+	}
+	if !PasswordNeedsRehash(stored) {
+		t.Error("plaintext does not need a rehash")
+	}
+
+	ok, err := ComparePassword(stored, "hunter2")
+	if err != nil || !ok {
+		t.Errorf("compare with the right password = %v, %v; want true, nil", ok, err)
+	}
+	ok, err = ComparePassword(stored, "hunter3")
+	if err != nil || ok {
+		t.Errorf("compare with a wrong password = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestPasswordNeedsRehashOnParameterChange(t *testing.T) {
+	stored, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	weaker := strings.Replace(stored, "m=65536", "m=32768", 1)
+	if !PasswordNeedsRehash(weaker) {
+		t.Error("a hash with older parameters does not need a rehash")
+	}
+}
@@ -0,0 +1,78 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"reflect"
+	"testing"
+)
+
+// TestThumbprintRFC7638 checks the example of RFC 7638 section 3.1 and the
+// Ed25519 example of RFC 8037 appendix A.3
+func TestThumbprintRFC7638(t *testing.T) {
+	tests := []struct {
+		name string
+		jwk  JWK
+		want string
+	}{
+		{
+			name: "RSA",
+			jwk: JWK{
+				Kty: "RSA",
+				N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMs" +
+					"tn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91Cb" +
+					"OpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+				E: "AQAB",
+			},
+			want: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		},
+		{
+			name: "Ed25519",
+			jwk:  JWK{Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+			want: "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub, err := tt.jwk.PublicKey()
+			if err != nil {
+				t.Fatalf("decode jwk: %v", err)
+			}
+			got, err := Thumbprint(pub)
+			if err != nil {
+				t.Fatalf("thumbprint: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("thumbprint = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+
+	for name, pub := range map[string]any{"RSA": &rsaKey.PublicKey, "Ed25519": edPub} {
+		t.Run(name, func(t *testing.T) {
+			jwk, err := NewJWK("kid", "alg", pub)
+			if err != nil {
+				t.Fatalf("new jwk: %v", err)
+			}
+			decoded, err := jwk.PublicKey()
+			if err != nil {
+				t.Fatalf("decode jwk: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, pub) {
+				t.Error("decoded key differs from the original")
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrMalformedToken   = errors.New("malformed token")
+	ErrUnknownKey       = errors.New("unknown signing key")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Header is the protected header of a compact JWS
+type Header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// SignCompact produces an RFC 7515 compact serialization of payload signed by s
+func SignCompact(kid string, s Signer, typ string, payload []byte) (string, error) {
+	header, err := json.Marshal(Header{Alg: s.Algorithm(), Kid: kid, Typ: typ})
+	if err != nil {
+		return "", err
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := s.Sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// CompactVerifier checks compact JWS tokens; a Keyring and a published JWKSet both do
+type CompactVerifier interface {
+	VerifyCompact(token string) (*Header, []byte, error)
+}
+
+// VerifyCompact checks a compact JWS against the keys of the ring and returns its header and payload
+func (k *Keyring) VerifyCompact(token string) (*Header, []byte, error) {
+	return verifyCompact(token, func(kid string) (string, crypto.PublicKey, error) {
+		s, ok := k.Lookup(kid)
+		if !ok {
+			return "", nil, ErrUnknownKey
+		}
+		return s.Algorithm(), s.Public(), nil
+	})
+}
+
+// VerifyCompact checks a compact JWS against the published keys and returns its header and payload
+func (set JWKSet) VerifyCompact(token string) (*Header, []byte, error) {
+	return verifyCompact(token, func(kid string) (string, crypto.PublicKey, error) {
+		for _, jwk := range set.Keys {
+			if jwk.Kid != kid {
+				continue
+			}
+			pub, err := jwk.PublicKey()
+			if err != nil {
+				return "", nil, err
+			}
+			return jwk.Alg, pub, nil
+		}
+		return "", nil, ErrUnknownKey
+	})
+}
+
+// verifyCompact checks token with the algorithm and public key lookup returns for its kid
+func verifyCompact(token string, lookup func(kid string) (string, crypto.PublicKey, error)) (*Header, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, ErrMalformedToken
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, ErrMalformedToken
+	}
+	var header Header
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, nil, ErrMalformedToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, ErrMalformedToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, ErrMalformedToken
+	}
+
+	alg, pub, err := lookup(header.Kid)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the algorithm is pinned by the key, never taken from the token
+	if header.Alg != alg {
+		return nil, nil, ErrInvalidSignature
+	}
+	if err := Verify(alg, pub, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, nil, err
+	}
+	return &header, payload, nil
+}
+
+// Verify checks a signature produced by a Signer of the given algorithm
+func Verify(algorithm string, pub crypto.PublicKey, data, signature []byte) error {
+	switch algorithm {
+	case RS256, PS256:
+		key, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an RSA public key", algorithm)
+		}
+		hash := sha256.Sum256(data)
+		var err error
+		if algorithm == PS256 {
+			err = rsa.VerifyPSS(key, crypto.SHA256, hash[:], signature, nil)
+		} else {
+			err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature)
+		}
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	case EdDSA:
+		key, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an Ed25519 public key", algorithm)
+		}
+		if !ed25519.Verify(key, data, signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+}
@@ -0,0 +1,65 @@
+package signer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Keyring holds every signing key known to the service. Only the active key
+// signs; the others are kept so that signatures they produced can still be verified
+type Keyring struct {
+	active string
+	keys   map[string]Signer
+	ids    []string
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string]Signer)}
+}
+
+// Add registers a key under kid
+func (k *Keyring) Add(kid string, s Signer) error {
+	if kid == "" {
+		return errors.New("key id must not be empty")
+	}
+	if _, ok := k.keys[kid]; ok {
+		return fmt.Errorf("duplicate key id: %s", kid)
+	}
+	k.keys[kid] = s
+	k.ids = append(k.ids, kid)
+	return nil
+}
+
+// SetActive selects the key used for new signatures
+func (k *Keyring) SetActive(kid string) error {
+	if _, ok := k.keys[kid]; !ok {
+		return fmt.Errorf("unknown key id: %s", kid)
+	}
+	k.active = kid
+	return nil
+}
+
+// Active returns the signing key and its id
+func (k *Keyring) Active() (string, Signer) {
+	return k.active, k.keys[k.active]
+}
+
+// Lookup returns the key registered under kid
+func (k *Keyring) Lookup(kid string) (Signer, bool) {
+	s, ok := k.keys[kid]
+	return s, ok
+}
+
+// JWKS publishes the public half of every key in the ring
+func (k *Keyring) JWKS() (JWKSet, error) {
+	set := JWKSet{Keys: make([]JWK, 0, len(k.ids))}
+	for _, kid := range k.ids {
+		s := k.keys[kid]
+		jwk, err := NewJWK(kid, s.Algorithm(), s.Public())
+		if err != nil {
+			return JWKSet{}, fmt.Errorf("key %s: %w", kid, err)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
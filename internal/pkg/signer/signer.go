@@ -0,0 +1,124 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Algorithm identifiers follow the JOSE names so clients can map them to a verifier directly
+const (
+	RS256 = "RS256" // RSA PKCS#1 v1.5 over SHA-256
+	PS256 = "PS256" // RSA-PSS over SHA-256
+	EdDSA = "EdDSA" // Ed25519
+)
+
+// Signer produces signatures over arbitrary payloads
+type Signer interface {
+	// Algorithm returns the identifier clients use to pick a verifier
+	Algorithm() string
+	Sign(data []byte) ([]byte, error)
+	Public() crypto.PublicKey
+}
+
+// New builds the signer for algorithm on top of key
+func New(algorithm string, key crypto.Signer) (Signer, error) {
+	switch algorithm {
+	case RS256, PS256:
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an RSA private key", algorithm)
+		}
+		if algorithm == PS256 {
+			return &rsaPSSSigner{key: rsaKey}, nil
+		}
+		return &rsaPKCS1v15Signer{key: rsaKey}, nil
+	case EdDSA:
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an Ed25519 private key", algorithm)
+		}
+		return &ed25519Signer{key: edKey}, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+}
+
+type rsaPKCS1v15Signer struct {
+	key *rsa.PrivateKey
+}
+
+func (s *rsaPKCS1v15Signer) Algorithm() string { return RS256 }
+
+func (s *rsaPKCS1v15Signer) Public() crypto.PublicKey { return &s.key.PublicKey }
+
+func (s *rsaPKCS1v15Signer) Sign(data []byte) ([]byte, error) {
+	hash := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(nil, s.key, crypto.SHA256, hash[:])
+}
+
+type rsaPSSSigner struct {
+	key *rsa.PrivateKey
+}
+
+func (s *rsaPSSSigner) Algorithm() string { return PS256 }
+
+func (s *rsaPSSSigner) Public() crypto.PublicKey { return &s.key.PublicKey }
+
+func (s *rsaPSSSigner) Sign(data []byte) ([]byte, error) {
+	hash := sha256.Sum256(data)
+	return rsa.SignPSS(rand.Reader, s.key, crypto.SHA256, hash[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+}
+
+type ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+func (s *ed25519Signer) Algorithm() string { return EdDSA }
+
+func (s *ed25519Signer) Public() crypto.PublicKey { return s.key.Public() }
+
+func (s *ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, data), nil
+}
+
+// LoadPrivateKey reads an RSA or Ed25519 private key from a PEM file
+func LoadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM format")
+	}
+
+	var parsedKey any
+	if block.Type == "PRIVATE KEY" {
+		parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	} else if block.Type == "RSA PRIVATE KEY" {
+		parsedKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		return nil, errors.New("unsupported key type: " + block.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsedKey.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	default:
+		return nil, errors.New("private key is neither RSA nor Ed25519")
+	}
+}
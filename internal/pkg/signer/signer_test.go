@@ -0,0 +1,189 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newTestSigner generates a fresh key for algorithm; 2048-bit RSA keeps the tests quick
+func newTestSigner(t *testing.T, algorithm string) Signer {
+	t.Helper()
+
+	var key crypto.Signer
+	var err error
+	if algorithm == EdDSA {
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	} else {
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		t.Fatalf("generate %s key: %v", algorithm, err)
+	}
+	s, err := New(algorithm, key)
+	if err != nil {
+		t.Fatalf("new %s signer: %v", algorithm, err)
+	}
+	return s
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	for _, alg := range []string{RS256, PS256, EdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			s := newTestSigner(t, alg)
+			ring := NewKeyring()
+			if err := ring.Add("k1", s); err != nil {
+				t.Fatalf("add: %v", err)
+			}
+			if err := ring.SetActive("k1"); err != nil {
+				t.Fatalf("set active: %v", err)
+			}
+
+			token, err := SignCompact("k1", s, "test+jws", []byte(`{"sub":1}`))
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+			header, payload, err := ring.VerifyCompact(token)
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if header.Alg != alg || header.Kid != "k1" || header.Typ != "test+jws" {
+				t.Errorf("header = %+v, want alg %s, kid k1, typ test+jws", header, alg)
+			}
+			if string(payload) != `{"sub":1}` {
+				t.Errorf("payload = %s", payload)
+			}
+
+			parts := strings.Split(token, ".")
+			tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":2}`)) + "." + parts[2]
+			if _, _, err := ring.VerifyCompact(tampered); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("tampered payload: err = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestNewRejectsMismatchedKey(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := New(RS256, edKey); err == nil {
+		t.Error("RS256 signer accepted an Ed25519 key")
+	}
+	if _, err := New("none", edKey); err == nil {
+		t.Error("unknown algorithm accepted")
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old, current := newTestSigner(t, EdDSA), newTestSigner(t, RS256)
+	ring := NewKeyring()
+	for kid, s := range map[string]Signer{"old": old, "current": current} {
+		if err := ring.Add(kid, s); err != nil {
+			t.Fatalf("add %s: %v", kid, err)
+		}
+	}
+	if err := ring.Add("old", old); err == nil {
+		t.Error("duplicate kid accepted")
+	}
+	if err := ring.SetActive("missing"); err == nil {
+		t.Error("unknown kid activated")
+	}
+	if err := ring.SetActive("current"); err != nil {
+		t.Fatalf("set active: %v", err)
+	}
+
+	kid, active := ring.Active()
+	if kid != "current" || active != current {
+		t.Fatalf("active key = %s, want current", kid)
+	}
+	fresh, err := SignCompact(kid, active, "test+jws", []byte("{}"))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	retired, err := SignCompact("old", old, "test+jws", []byte("{}"))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	set, err := ring.JWKS()
+	if err != nil {
+		t.Fatalf("jwks: %v", err)
+	}
+	if len(set.Keys) != 2 {
+		t.Fatalf("jwks has %d keys, want 2", len(set.Keys))
+	}
+	for _, verifier := range []CompactVerifier{ring, set} {
+		for _, token := range []string{fresh, retired} {
+			if _, _, err := verifier.VerifyCompact(token); err != nil {
+				t.Errorf("%T rejected a token of a published key: %v", verifier, err)
+			}
+		}
+	}
+
+	unknown, _ := SignCompact("gone", newTestSigner(t, EdDSA), "test+jws", []byte("{}"))
+	if _, _, err := ring.VerifyCompact(unknown); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown kid: err = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestVerifyCompactRejectsAlgorithmMismatch(t *testing.T) {
+	ed := newTestSigner(t, EdDSA)
+	rs := newTestSigner(t, RS256)
+	ring := NewKeyring()
+	_ = ring.Add("ed", ed)
+	_ = ring.Add("rs", rs)
+	set, err := ring.JWKS()
+	if err != nil {
+		t.Fatalf("jwks: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		header Header
+		signer Signer
+	}{
+		{"RS256 header on an EdDSA key", Header{Alg: RS256, Kid: "ed"}, ed},
+		{"PS256 header on an RS256 key", Header{Alg: PS256, Kid: "rs"}, rs},
+		{"none", Header{Alg: "none", Kid: "rs"}, rs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := forgeToken(t, tt.header, tt.signer)
+			for _, verifier := range []CompactVerifier{ring, set} {
+				if _, _, err := verifier.VerifyCompact(token); !errors.Is(err, ErrInvalidSignature) {
+					t.Errorf("%T: err = %v, want %v", verifier, err, ErrInvalidSignature)
+				}
+			}
+		})
+	}
+}
+
+// forgeToken signs a token with s under an arbitrary header
+func forgeToken(t *testing.T, header Header, s Signer) string {
+	t.Helper()
+
+	rawHeader, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("marshal header: %v", err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(rawHeader) + "." + base64.RawURLEncoding.EncodeToString([]byte("{}"))
+	signature, err := s.Sign([]byte(input))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyCompactMalformed(t *testing.T) {
+	ring := NewKeyring()
+	for _, token := range []string{"", "a.b", "a.b.c.d", "!!!.e30.c2ln", "e30.!!!.c2ln"} {
+		if _, _, err := ring.VerifyCompact(token); !errors.Is(err, ErrMalformedToken) {
+			t.Errorf("VerifyCompact(%q) err = %v, want %v", token, err, ErrMalformedToken)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RegisterDevice records that a user signed in from hwid. A device seen for
+// the first time, or a previously deactivated one, takes a slot of the
+// per-user device limit
+func (s *Service) RegisterDevice(userID uint, hwid string) (*models.Device, error) {
+	var device models.Device
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// lock the user so concurrent logins from new machines are counted one at a time
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err := tx.Where("user_id = ? AND hwid = ?", userID, hwid).First(&device).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && device.Active {
+			device.LastSeenAt = now
+			return tx.Model(&device).Update("last_seen_at", now).Error
+		}
+
+		var active int64
+		if err := tx.Model(&models.Device{}).Where("user_id = ? AND active = true", userID).Count(&active).Error; err != nil {
+			return err
+		}
+		if active >= int64(s.cfg.Devices.MaxPerUser) {
+			return ErrDeviceLimitReached
+		}
+
+		device.UserID = userID
+		device.HWID = hwid
+		device.Active = true
+		device.LastSeenAt = now
+		device.DeactivatedAt = nil
+		return tx.Save(&device).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// GetDevice returns the device of the user registered under hwid, active or not
+func (s *Service) GetDevice(userID uint, hwid string) (*models.Device, error) {
+	var user models.User
+	if err := s.db.Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	var device models.Device
+	if err := s.db.Where("user_id = ? AND hwid = ?", userID, hwid).First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+// ListDevices returns every device of a user, active ones first
+func (s *Service) ListDevices(userID uint) ([]models.Device, error) {
+	var devices []models.Device
+	if err := s.db.Where("user_id = ?", userID).Order("active DESC, last_seen_at DESC").Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// DeactivateDevice releases a device of the user so its slot can be taken by
+// another machine. Releases are rate limited by the configured cooldown; the
+// returned time is when the next release becomes possible
+func (s *Service) DeactivateDevice(userID, deviceID uint) (*time.Time, error) {
+	var nextRelease time.Time
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if user.DeviceReleasedAt != nil {
+			nextRelease = user.DeviceReleasedAt.Add(s.cfg.Devices.ReleaseCooldown)
+			if now.Before(nextRelease) {
+				return ErrReleaseCooldown
+			}
+		}
+
+		var device models.Device
+		if err := tx.Where("id = ? AND user_id = ? AND active = true", deviceID, userID).First(&device).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDeviceNotFound
+			}
+			return err
+		}
+
+		if err := tx.Model(&device).Updates(map[string]any{"active": false, "deactivated_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Updates(map[string]any{
+			"device_released_at": now,
+			"device_releases":    gorm.Expr("device_releases + 1"),
+		}).Error
+	})
+	if errors.Is(err, ErrReleaseCooldown) {
+		return &nextRelease, err
+	}
+	return nil, err
+}
+
+// ReleaseDevice deactivates a device of the user on behalf of support staff.
+// Unlike DeactivateDevice it neither checks nor starts the user's release cooldown
+func (s *Service) ReleaseDevice(userID, deviceID uint) error {
+	res := s.db.Model(&models.Device{}).
+		Where("id = ? AND user_id = ? AND active = true", deviceID, userID).
+		Updates(map[string]any{"active": false, "deactivated_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"gorm.io/gorm"
+)
+
+// Key statuses accepted by KeyFilter
+const (
+	KeyStatusUnused  = "unused"
+	KeyStatusUsed    = "used"
+	KeyStatusExpired = "expired"
+	KeyStatusRevoked = "revoked"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// KeyFilter selects invitation keys; zero values do not filter
+type KeyFilter struct {
+	// Status is one of the KeyStatus constants. Unused keys can still be
+	// redeemed; used keys are used up, expired ones lapsed before that
+	Status    string
+	Purpose   string
+	CreatedBy string
+	// From and To bound the creation time, inclusive
+	From, To *time.Time
+	// Page starts at 1; PageSize defaults to 50 and is capped at 200
+	Page, PageSize int
+}
+
+// ListKeys returns a page of the keys matching filter, newest first, and the number of all matches
+func (s *Service) ListKeys(filter KeyFilter) (*models.KeyList, error) {
+	page, size := pagination(filter.Page, filter.PageSize)
+
+	query := s.db.Model(&models.OneTimeLink{})
+	now := time.Now()
+	switch filter.Status {
+	case "":
+	case KeyStatusUnused:
+		query = query.Where("used = false AND (expires_at IS NULL OR expires_at > ?)", now)
+	case KeyStatusUsed:
+		query = query.Where("used = true AND revoked_at IS NULL")
+	case KeyStatusExpired:
+		query = query.Where("used = false AND expires_at <= ?", now)
+	case KeyStatusRevoked:
+		query = query.Where("revoked_at IS NOT NULL")
+	default:
+		return nil, ErrUnknownKeyStatus
+	}
+	if filter.Purpose != "" {
+		query = query.Where("purpose = ?", filter.Purpose)
+	}
+	if filter.CreatedBy != "" {
+		query = query.Where("created_by = ?", filter.CreatedBy)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	list := models.KeyList{Keys: []models.OneTimeLink{}, Page: page, PageSize: size}
+	if err := query.Session(&gorm.Session{}).Count(&list.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := query.Preload("Plan").Order("created_at DESC, id DESC").
+		Offset((page - 1) * size).Limit(size).
+		Find(&list.Keys).Error; err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (s *Service) GetKey(key string) (*models.OneTimeLink, error) {
+	var link models.OneTimeLink
+	if err := s.db.Preload("Plan").Where("key = ?", key).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+// RevokeKey withdraws a key that can still be redeemed. Accounts already
+// registered with it are not affected
+func (s *Service) RevokeKey(key string) (*models.OneTimeLink, error) {
+	link, err := s.GetKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// the used flag is what redemption checks, so a revoked key is simply used up early
+	now := time.Now()
+	res := s.db.Model(&models.OneTimeLink{}).
+		Where("id = ? AND used = false", link.ID).
+		Updates(map[string]any{"used": true, "revoked_at": now})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrKeyUsedUp
+	}
+
+	link.Used = true
+	link.RevokedAt = &now
+	return link, nil
+}
+
+// KeyRedeemers lists the users who redeemed a key, in redemption order
+func (s *Service) KeyRedeemers(linkID uint) ([]models.KeyRedeemer, error) {
+	redeemers := []models.KeyRedeemer{}
+	err := s.db.Model(&models.KeyRedemption{}).
+		Select("key_redemptions.user_id, users.email, users.username, key_redemptions.created_at AS redeemed_at").
+		Joins("LEFT JOIN users ON users.id = key_redemptions.user_id").
+		Where("key_redemptions.link_id = ?", linkID).
+		Order("key_redemptions.created_at").
+		Scan(&redeemers).Error
+	if err != nil {
+		return nil, err
+	}
+	return redeemers, nil
+}
+
+// pagination normalizes a 1-based page number and a page size
+func pagination(page, size int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = defaultPageSize
+	}
+	return page, min(size, maxPageSize)
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *Service) GetOrganizationByName(name string) (*models.Organization, error) {
+	var org models.Organization
+	if err := s.db.Where("name = ?", name).First(&org).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// SaveOrganization creates the organization or updates the seat count of the one with the same name
+func (s *Service) SaveOrganization(org *models.Organization) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"seats", "updated_at"}),
+	}).Create(org).Error
+}
+
+// SetUserOrganization moves a user into the seat pool of an organization
+func (s *Service) SetUserOrganization(userID, orgID uint) error {
+	return s.db.Model(&models.User{ID: userID}).Update("organization_id", orgID).Error
+}
+
+// CheckoutSeat takes a concurrent seat of the user's organization for hwid.
+// Users outside an organization need no seat and get a nil lease. A machine
+// that already holds a live lease gets it back renewed instead of a second seat
+func (s *Service) CheckoutSeat(user *models.User, hwid string) (*models.Lease, error) {
+	if user.OrganizationID == nil {
+		return nil, nil
+	}
+
+	var lease models.Lease
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// the organization row serializes checkouts, so seats can not be oversold
+		var org models.Organization
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&org, *user.OrganizationID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		expiresAt := now.Add(s.cfg.Leases.TTL)
+		live := tx.Where("organization_id = ? AND released_at IS NULL AND expires_at > ?", org.ID, now)
+
+		err := live.Session(&gorm.Session{}).Where("user_id = ? AND hwid = ?", user.ID, hwid).First(&lease).Error
+		if err == nil {
+			lease.ExpiresAt = expiresAt
+			return tx.Model(&lease).Update("expires_at", expiresAt).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var taken int64
+		if err := live.Session(&gorm.Session{}).Model(&models.Lease{}).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken >= int64(org.Seats) {
+			return ErrNoSeatsAvailable
+		}
+
+		id, err := newLeaseID()
+		if err != nil {
+			return err
+		}
+		lease = models.Lease{
+			ID:             id,
+			OrganizationID: org.ID,
+			UserID:         user.ID,
+			HWID:           hwid,
+			ExpiresAt:      expiresAt,
+		}
+		return tx.Create(&lease).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// RenewLease extends a live lease held by hwid; a lapsed lease has to be checked out again through login.
+// A lease whose holder was locked out, whose license ran out or who left the
+// organization is not extended
+func (s *Service) RenewLease(leaseID, hwid string) (*models.Lease, error) {
+	var lease models.Lease
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND hwid = ?", leaseID, hwid).
+			First(&lease).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLeaseNotFound
+			}
+			return err
+		}
+
+		now := time.Now()
+		if lease.ReleasedAt != nil || !now.Before(lease.ExpiresAt) {
+			return ErrLeaseExpired
+		}
+
+		var user models.User
+		if err := tx.First(&user, lease.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLeaseNotFound
+			}
+			return err
+		}
+		if err := CheckAccount(&user); err != nil {
+			return err
+		}
+		if LicenseExpired(&user, now) {
+			return ErrLicenseExpired
+		}
+		if user.OrganizationID == nil || *user.OrganizationID != lease.OrganizationID {
+			return ErrLeaseExpired
+		}
+
+		lease.ExpiresAt = now.Add(s.cfg.Leases.TTL)
+		return tx.Model(&lease).Update("expires_at", lease.ExpiresAt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// ReleaseLease gives the seat back before the lease lapses
+func (s *Service) ReleaseLease(leaseID, hwid string) error {
+	res := s.db.Model(&models.Lease{}).
+		Where("id = ? AND hwid = ? AND released_at IS NULL", leaseID, hwid).
+		Update("released_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLeaseNotFound
+	}
+	return nil
+}
+
+// releaseUserLeases frees every seat held by the user
+func releaseUserLeases(tx *gorm.DB, userID uint, now time.Time) error {
+	return tx.Model(&models.Lease{}).
+		Where("user_id = ? AND released_at IS NULL", userID).
+		Update("released_at", now).Error
+}
+
+func newLeaseID() (string, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(idBytes), nil
+}
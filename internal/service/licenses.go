@@ -0,0 +1,52 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/license"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+)
+
+// IssueLicense prepares the claims of an offline license for one machine of
+// the user. The machine takes a device slot exactly like a regular login, and
+// the license never outlives the user's own access period. Validity is capped
+// by the configured license validity, so revocations are listed for as long as
+// the license can be used
+func (s *Service) IssueLicense(user *models.User, hwid string, entitlements []string, validity time.Duration) (*license.Claims, error) {
+	if validity <= 0 || validity > s.cfg.Licenses.Validity {
+		return nil, ErrLicenseTooLong
+	}
+
+	now := time.Now()
+	if LicenseExpired(user, now) {
+		return nil, ErrLicenseExpired
+	}
+
+	if _, err := s.RegisterDevice(user.ID, hwid); err != nil {
+		return nil, err
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		s.logger.Errorf("failed to generate license id: %s", err.Error())
+		return nil, err
+	}
+
+	notAfter := now.Add(validity)
+	if user.LicenseExpiresAt != nil && user.LicenseExpiresAt.Before(notAfter) {
+		notAfter = *user.LicenseExpiresAt
+	}
+
+	return &license.Claims{
+		ID:           hex.EncodeToString(idBytes),
+		UserID:       user.ID,
+		Email:        user.Email,
+		HWID:         hwid,
+		Entitlements: entitlements,
+		IssuedAt:     now.Unix(),
+		NotBefore:    now.Unix(),
+		NotAfter:     notAfter.Unix(),
+	}, nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *Service) GetPlanByName(name string) (*models.Plan, error) {
+	var plan models.Plan
+	if err := s.db.Where("name = ?", name).First(&plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanNotFound
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (s *Service) ListPlans() ([]models.Plan, error) {
+	var plans []models.Plan
+	if err := s.db.Order("name").Find(&plans).Error; err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// SavePlan creates the plan or replaces the features and limits of the plan with the same name
+func (s *Service) SavePlan(plan *models.Plan) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"features", "limits", "duration_days", "updated_at"}),
+	}).Create(plan).Error
+}
+
+// SetUserPlan moves a user to another plan
+func (s *Service) SetUserPlan(userID, planID uint) error {
+	return s.db.Model(&models.User{ID: userID}).Update("plan_id", planID).Error
+}
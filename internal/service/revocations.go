@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"gorm.io/gorm"
+)
+
+// RevokeApproval invalidates the single approval with the given id
+func (s *Service) RevokeApproval(approvalID, reason string) (*models.Revocation, error) {
+	revocation := models.Revocation{ApprovalID: approvalID, Reason: reason}
+	if err := s.db.Create(&revocation).Error; err != nil {
+		return nil, err
+	}
+	return &revocation, nil
+}
+
+// RevokeUser invalidates every approval issued to the user so far. Later logins are not affected
+func (s *Service) RevokeUser(userID uint, reason string) (*models.Revocation, error) {
+	var user models.User
+	if err := s.db.Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	revocation := models.Revocation{UserID: &userID, Reason: reason}
+	if err := s.db.Create(&revocation).Error; err != nil {
+		return nil, err
+	}
+	return &revocation, nil
+}
+
+// RevokeDevice invalidates every approval issued so far to one device of the user
+func (s *Service) RevokeDevice(userID uint, hwid, reason string) (*models.Revocation, error) {
+	if _, err := s.GetDevice(userID, hwid); err != nil {
+		return nil, err
+	}
+
+	revocation := models.Revocation{UserID: &userID, HWID: hwid, Reason: reason}
+	if err := s.db.Create(&revocation).Error; err != nil {
+		return nil, err
+	}
+	return &revocation, nil
+}
+
+// ListRevocations returns the revocations made after since, oldest first
+func (s *Service) ListRevocations(since time.Time) ([]models.Revocation, error) {
+	var revocations []models.Revocation
+	if err := s.db.Where("created_at > ?", since).Order("created_at").Find(&revocations).Error; err != nil {
+		return nil, err
+	}
+	return revocations, nil
+}
+
+// ApprovalRevoked reports whether an approval, identified by its id, user, hwid
+// and issue time, is covered by a revocation
+func (s *Service) ApprovalRevoked(approvalID string, userID uint, hwid string, issuedAt time.Time) (bool, error) {
+	// user and device revocations only cover approvals issued before them
+	query := s.db.Model(&models.Revocation{}).
+		Where("(user_id = ? AND (hwid = '' OR hwid = ?) AND created_at >= ?) OR (approval_id <> '' AND approval_id = ?)",
+			userID, hwid, issuedAt, approvalID)
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -5,48 +5,146 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"time"
 
 	lgg "github.com/ruziba3vich/prodonik_lgger"
+	"github.com/ruziba3vich/tokenizer/internal/config"
 	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/pkg/helper"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidKey  = errors.New("invalid or used key")
+	ErrKeyExpired  = errors.New("key expired")
+	ErrKeyNotFound = errors.New("key not found")
+	ErrKeyUsedUp   = errors.New("key is already used up or revoked")
+
+	ErrUnknownKeyStatus = errors.New("unknown key status")
+
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrUnknownRole   = errors.New("unknown role")
+
+	ErrUserDisabled          = errors.New("account disabled")
+	ErrPasswordResetRequired = errors.New("password reset required")
+	ErrInvalidResetCode      = errors.New("invalid or expired reset code")
+	ErrPlanNotFound          = errors.New("plan not found")
+
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrNoSeatsAvailable     = errors.New("no seats available")
+	ErrLeaseNotFound        = errors.New("lease not found")
+	ErrLeaseExpired         = errors.New("lease expired")
+
+	ErrLicenseExpired    = errors.New("license expired")
+	ErrLicenseTooLong    = errors.New("license validity exceeds the configured offline license validity")
+	ErrRenewalNeedsPlan  = errors.New("renewal keys need a plan with a duration")
+	ErrUnknownKeyPurpose = errors.New("unknown key purpose")
+	ErrTrialRenewal      = errors.New("trial keys cannot be renewal keys")
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reused, session revoked")
+
+	ErrDeviceLimitReached = errors.New("device limit reached")
+	ErrDeviceNotFound     = errors.New("device not found")
+	ErrReleaseCooldown    = errors.New("device release cooldown has not passed")
 )
 
 type (
 	Service struct {
 		db     *gorm.DB
 		logger *lgg.Logger
+		cfg    *config.Config
+	}
+
+	// LinkOptions controls how an invitation key is minted
+	LinkOptions struct {
+		// TTL is how long the key stays valid; zero means it never expires
+		TTL time.Duration
+		// MaxUses is how many accounts may register with the key; values below 1 mean 1
+		MaxUses int
+		// Plan is the name of the plan granted to users of the key; empty grants none
+		Plan string
+		// Purpose is models.KeyPurposeRegister (the default) or models.KeyPurposeRenewal
+		Purpose string
+		// Trial makes a registration key that grants a trial; Plan defaults to the configured trial plan
+		Trial bool
+		// Organization is the name of the seat pool users of the key join
+		Organization string
+		// CreatedBy names the admin principal minting the key
+		CreatedBy string
 	}
 )
 
-func NewService(db *gorm.DB, logger *lgg.Logger) *Service {
+func NewService(db *gorm.DB, logger *lgg.Logger, cfg *config.Config) *Service {
 	return &Service{
 		db:     db,
 		logger: logger,
+		cfg:    cfg,
 	}
 }
 
-func (h *Service) GenerateOneTimeLink() (string, error) {
+// GenerateOneTimeLink mints a new invitation key and returns it together with its shareable url
+func (h *Service) GenerateOneTimeLink(opts LinkOptions) (*models.OneTimeLink, string, error) {
 	keyBytes := make([]byte, 8)
 	if _, err := rand.Read(keyBytes); err != nil {
 		h.logger.Errorf("failed to generate key: %s", err.Error())
-		return "", err
+		return nil, "", err
 	}
 	key := hex.EncodeToString(keyBytes)
 
-	link := models.OneTimeLink{Key: key, Used: false}
-	if err := h.db.Create(&link).Error; err != nil {
+	link := models.OneTimeLink{Key: key, Used: false, MaxUses: max(opts.MaxUses, 1), Purpose: opts.Purpose, Trial: opts.Trial, CreatedBy: opts.CreatedBy, CreatedAt: time.Now()}
+	if link.Purpose == "" {
+		link.Purpose = models.KeyPurposeRegister
+	}
+	if link.Purpose != models.KeyPurposeRegister && link.Purpose != models.KeyPurposeRenewal {
+		return nil, "", ErrUnknownKeyPurpose
+	}
+	if link.Trial && link.Purpose != models.KeyPurposeRegister {
+		return nil, "", ErrTrialRenewal
+	}
+	planName := opts.Plan
+	if link.Trial && planName == "" {
+		planName = h.cfg.Trial.Plan
+	}
+	if planName != "" {
+		plan, err := h.GetPlanByName(planName)
+		if err != nil {
+			return nil, "", err
+		}
+		link.PlanID = &plan.ID
+		link.Plan = plan
+	}
+	if opts.Organization != "" {
+		org, err := h.GetOrganizationByName(opts.Organization)
+		if err != nil {
+			return nil, "", err
+		}
+		link.OrganizationID = &org.ID
+	}
+	if link.Purpose == models.KeyPurposeRenewal && (link.Plan == nil || link.Plan.DurationDays <= 0) {
+		return nil, "", ErrRenewalNeedsPlan
+	}
+	if opts.TTL > 0 {
+		expiresAt := link.CreatedAt.Add(opts.TTL)
+		link.ExpiresAt = &expiresAt
+	}
+	if err := h.db.Omit(clause.Associations).Create(&link).Error; err != nil {
 		h.logger.Errorf("failed to store one-time key: %s", err.Error())
-		return "", err
+		return nil, "", err
 	}
 
-	url := fmt.Sprintf("https://fintrack.vintorum.com/key/%s", key)
-	return url, nil
+	url := h.cfg.Links.BaseURL + key
+	return &link, url, nil
 }
 
 func (s *Service) CreateUser(payload *models.RegisterPayload) error {
-	var link models.OneTimeLink
-	if err := s.db.Where("key = ? AND used = false", payload.Key).First(&link).Error; err != nil {
-		return fmt.Errorf("invalid or used key")
+	hashed, err := helper.HashPassword(payload.Password)
+	if err != nil {
+		s.logger.Errorf("failed to hash password: %s", err.Error())
+		return err
 	}
 
 	user := models.User{
@@ -55,39 +153,113 @@ func (s *Service) CreateUser(payload *models.RegisterPayload) error {
 		Email:     payload.Email,
 		Phone:     payload.Phone,
 		Username:  payload.Username,
-		Password:  payload.Password,
+		Password:  hashed,
 	}
 
-	err := s.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&user).Error; err != nil {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		link, err := lockKey(tx, payload.Key, models.KeyPurposeRegister)
+		if err != nil {
 			return err
 		}
 
-		link.Used = true
-		if err := tx.Save(&link).Error; err != nil {
+		user.PlanID = link.PlanID
+		user.OrganizationID = link.OrganizationID
+		switch {
+		case link.Trial:
+			expiresAt := time.Now().AddDate(0, 0, s.cfg.Trial.Days)
+			user.LicenseExpiresAt = &expiresAt
+			user.Trial = true
+		case link.Plan != nil && link.Plan.DurationDays > 0:
+			expiresAt := time.Now().AddDate(0, 0, link.Plan.DurationDays)
+			user.LicenseExpiresAt = &expiresAt
+		}
+		if err := tx.Create(&user).Error; err != nil {
 			return err
 		}
-		return nil
+
+		return redeemKey(tx, link, user.ID)
 	})
+}
 
-	return err
+// lockKey loads a usable key of the given purpose. The row stays locked until
+// the transaction ends, so parallel redemptions of the same key are serialized on it
+func lockKey(tx *gorm.DB, key, purpose string) (*models.OneTimeLink, error) {
+	var link models.OneTimeLink
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Plan").
+		Where("key = ? AND purpose = ? AND used = false", key, purpose).
+		First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+	if link.Expired(time.Now()) {
+		return nil, ErrKeyExpired
+	}
+	return &link, nil
+}
+
+// redeemKey records one use of a key locked by lockKey
+func redeemKey(tx *gorm.DB, link *models.OneTimeLink, userID uint) error {
+	// the counter is only bumped while it is below the limit, so the key
+	// can never be redeemed more than MaxUses times
+	res := tx.Model(&models.OneTimeLink{}).
+		Where("id = ? AND used = false AND uses_count < max_uses", link.ID).
+		Updates(map[string]any{
+			"uses_count": gorm.Expr("uses_count + 1"),
+			"used":       gorm.Expr("uses_count + 1 >= max_uses"),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidKey
+	}
+
+	return tx.Create(&models.KeyRedemption{LinkID: link.ID, UserID: userID}).Error
 }
 
 func (s *Service) GetUserByEmailAndPassword(email, password string) (*models.User, error) {
 	var user models.User
 
 	// Only search by email
-	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := s.db.Preload("Plan").Where("email = ?", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 
 	// Then compare password manually
-	if user.Password != password {
+	ok, err := helper.ComparePassword(user.Password, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
 		return nil, fmt.Errorf("invalid password")
 	}
 
+	// legacy plaintext rows and outdated hashes are upgraded on a successful login
+	if helper.PasswordNeedsRehash(user.Password) {
+		hashed, err := helper.HashPassword(password)
+		if err != nil {
+			s.logger.Errorf("failed to rehash password of user %d: %s", user.ID, err.Error())
+		} else if err := s.db.Model(&user).Update("password", hashed).Error; err != nil {
+			s.logger.Errorf("failed to store rehashed password of user %d: %s", user.ID, err.Error())
+		}
+	}
+
+	return &user, nil
+}
+
+func (s *Service) GetUserByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := s.db.Preload("Plan").Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
 	return &user, nil
 }
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	lgg "github.com/ruziba3vich/prodonik_lgger"
+	"github.com/ruziba3vich/tokenizer/internal/config"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/pkg/helper"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestService connects to the postgres database in TEST_DATABASE_DSN and
+// skips the test when it is not set, except in CI where a skip would hide a
+// regression. Tests use unique keys and emails, so they can share one database
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatal("TEST_DATABASE_DSN must be set in CI")
+		}
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := helper.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	log, err := lgg.NewLogger(filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+
+	cfg := &config.Config{
+		Links:  config.LinksConfig{BaseURL: "https://example.com/key/"},
+		Trial:  config.TrialConfig{Days: 14},
+		Leases: config.LeasesConfig{TTL: 5 * time.Minute},
+	}
+	return NewService(db, log, cfg)
+}
+
+// registerConcurrently fires n parallel registrations at key and returns how many succeeded
+func registerConcurrently(t *testing.T, s *Service, key string, n int) int {
+	t.Helper()
+
+	run := time.Now().UnixNano()
+	start := make(chan struct{})
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = s.CreateUser(&models.RegisterPayload{
+				FirstName: "Race",
+				LastName:  "Runner",
+				Email:     fmt.Sprintf("race-%d-%d@example.com", run, i),
+				Username:  fmt.Sprintf("race-%d-%d", run, i),
+				Password:  "securepassword123",
+				Key:       key,
+			})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	wins := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			wins++
+		case !errors.Is(err, ErrInvalidKey):
+			t.Errorf("losing registration failed with %v, want %v", err, ErrInvalidKey)
+		}
+	}
+	return wins
+}
+
+func TestCreateUserSingleUseKeyConcurrently(t *testing.T) {
+	s := newTestService(t)
+
+	link, _, err := s.GenerateOneTimeLink(LinkOptions{})
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	if wins := registerConcurrently(t, s, link.Key, 20); wins != 1 {
+		t.Fatalf("%d registrations won a single-use key, want exactly 1", wins)
+	}
+	assertRedemptions(t, s, link.ID, 1)
+}
+
+func TestCreateUserMultiUseKeyConcurrently(t *testing.T) {
+	s := newTestService(t)
+
+	link, _, err := s.GenerateOneTimeLink(LinkOptions{MaxUses: 3})
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	if wins := registerConcurrently(t, s, link.Key, 20); wins != 3 {
+		t.Fatalf("%d registrations won a key with max_uses 3, want exactly 3", wins)
+	}
+	assertRedemptions(t, s, link.ID, 3)
+}
+
+// assertRedemptions checks that the key is used up after exactly want redemptions
+func assertRedemptions(t *testing.T, s *Service, linkID uint, want int) {
+	t.Helper()
+
+	var link models.OneTimeLink
+	if err := s.db.First(&link, linkID).Error; err != nil {
+		t.Fatalf("reload key: %v", err)
+	}
+	if link.UsesCount != want || !link.Used {
+		t.Errorf("key has uses_count %d and used %v, want %d and true", link.UsesCount, link.Used, want)
+	}
+
+	var redemptions int64
+	if err := s.db.Model(&models.KeyRedemption{}).Where("link_id = ?", linkID).Count(&redemptions).Error; err != nil {
+		t.Fatalf("count redemptions: %v", err)
+	}
+	if redemptions != int64(want) {
+		t.Errorf("%d redemptions recorded, want %d", redemptions, want)
+	}
+}
+
+func TestDisableUserReleasesLeases(t *testing.T) {
+	s := newTestService(t)
+
+	run := time.Now().UnixNano()
+	org := models.Organization{Name: fmt.Sprintf("org-%d", run), Seats: 1}
+	if err := s.SaveOrganization(&org); err != nil {
+		t.Fatalf("save organization: %v", err)
+	}
+	user := models.User{
+		FirstName:      "Seat",
+		LastName:       "Holder",
+		Email:          fmt.Sprintf("seat-%d@example.com", run),
+		Username:       fmt.Sprintf("seat-%d", run),
+		Password:       "-",
+		OrganizationID: &org.ID,
+	}
+	if err := s.db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	lease, err := s.CheckoutSeat(&user, "hwid-1")
+	if err != nil {
+		t.Fatalf("checkout seat: %v", err)
+	}
+	if err := s.DisableUser(user.ID, "test"); err != nil {
+		t.Fatalf("disable user: %v", err)
+	}
+
+	if err := s.db.First(lease, "id = ?", lease.ID).Error; err != nil {
+		t.Fatalf("reload lease: %v", err)
+	}
+	if lease.ReleasedAt == nil {
+		t.Error("lease of a disabled user is still held")
+	}
+	if _, err := s.RenewLease(lease.ID, "hwid-1"); err == nil {
+		t.Error("lease of a disabled user was renewed")
+	}
+}
+
+func TestDeleteUserRevokesApprovals(t *testing.T) {
+	s := newTestService(t)
+
+	run := time.Now().UnixNano()
+	user := models.User{
+		FirstName: "Gone",
+		LastName:  "Soon",
+		Email:     fmt.Sprintf("gone-%d@example.com", run),
+		Username:  fmt.Sprintf("gone-%d", run),
+		Password:  "-",
+	}
+	if err := s.db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	if err := s.DeleteUser(user.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+
+	var revocations int64
+	if err := s.db.Model(&models.Revocation{}).Where("user_id = ? AND approval_id = ''", user.ID).Count(&revocations).Error; err != nil {
+		t.Fatalf("count revocations: %v", err)
+	}
+	if revocations != 1 {
+		t.Errorf("%d revocations recorded for the deleted user, want 1", revocations)
+	}
+}
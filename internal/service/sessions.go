@@ -0,0 +1,136 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateSession starts a new refresh token family for the user and returns its first token
+func (s *Service) CreateSession(userID uint) (string, *models.RefreshToken, error) {
+	family, err := randomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+	return s.issueRefreshToken(s.db, userID, family)
+}
+
+// RotateRefreshToken exchanges a refresh token for the next one of its family.
+// Presenting a token that was already rotated means it leaked, so the whole
+// family is revoked and ErrRefreshTokenReused returned
+func (s *Service) RotateRefreshToken(raw string) (string, *models.RefreshToken, error) {
+	var (
+		next    *models.RefreshToken
+		nextRaw string
+		reused  bool
+	)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashToken(raw)).
+			First(&current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		now := time.Now()
+		if current.RevokedAt != nil {
+			reused = true
+			return revokeFamily(tx, current.FamilyID, now)
+		}
+		if !now.Before(current.ExpiresAt) {
+			return ErrRefreshTokenExpired
+		}
+
+		if err := tx.Model(&current).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		var err error
+		nextRaw, next, err = s.issueRefreshToken(tx, current.UserID, current.FamilyID)
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if reused {
+		s.logger.Println("revoked a session after its refresh token was reused")
+		return "", nil, ErrRefreshTokenReused
+	}
+	return nextRaw, next, nil
+}
+
+// RevokeSession ends the session a refresh token belongs to
+func (s *Service) RevokeSession(raw string) error {
+	var token models.RefreshToken
+	if err := s.db.Where("token_hash = ?", hashToken(raw)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+	return revokeFamily(s.db, token.FamilyID, time.Now())
+}
+
+// SessionActive reports whether a refresh token family still has an unrevoked token
+func (s *Service) SessionActive(familyID string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL AND expires_at > ?", familyID, time.Now()).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *Service) issueRefreshToken(tx *gorm.DB, userID uint, family string) (string, *models.RefreshToken, error) {
+	raw, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  family,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(s.cfg.Sessions.RefreshTTL),
+	}
+	if err := tx.Create(&token).Error; err != nil {
+		return "", nil, err
+	}
+	return raw, &token, nil
+}
+
+func revokeFamily(tx *gorm.DB, family string, now time.Time) error {
+	return tx.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", family).
+		Update("revoked_at", now).Error
+}
+
+func revokeUserSessions(tx *gorm.DB, userID uint, now time.Time) error {
+	return tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+// hashToken is how refresh tokens are looked up; they are high entropy, so no salt is needed
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RenewUser redeems a renewal code for an existing account. The plan of the
+// code replaces the user's plan and its duration is added to the current
+// expiry, or to now when the license has already lapsed or was a trial.
+// Renewing ends the trial
+func (s *Service) RenewUser(userID uint, code string) (*models.User, error) {
+	var user models.User
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		link, err := lockKey(tx, code, models.KeyPurposeRenewal)
+		if err != nil {
+			return err
+		}
+		if link.Plan == nil || link.Plan.DurationDays <= 0 {
+			return ErrRenewalNeedsPlan
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		start := time.Now()
+		if !user.Trial && user.LicenseExpiresAt != nil && user.LicenseExpiresAt.After(start) {
+			start = *user.LicenseExpiresAt
+		}
+		expiresAt := start.AddDate(0, 0, link.Plan.DurationDays)
+
+		if err := tx.Model(&user).Updates(map[string]any{
+			"plan_id":            link.PlanID,
+			"license_expires_at": expiresAt,
+			"trial":              false,
+		}).Error; err != nil {
+			return err
+		}
+		user.Trial = false
+		user.PlanID = link.PlanID
+		user.Plan = link.Plan
+		user.LicenseExpiresAt = &expiresAt
+
+		return redeemKey(tx, link, user.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RemainingDays returns the whole or partial days left until the license of the user expires
+func RemainingDays(user *models.User, now time.Time) int {
+	if user.LicenseExpiresAt == nil || !now.Before(*user.LicenseExpiresAt) {
+		return 0
+	}
+	left := user.LicenseExpiresAt.Sub(now)
+	return int((left + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+// LicenseExpired reports whether the access period of the user has lapsed
+func LicenseExpired(user *models.User, now time.Time) bool {
+	return user.LicenseExpiresAt != nil && !now.Before(*user.LicenseExpiresAt)
+}
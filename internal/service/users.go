@@ -0,0 +1,262 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ruziba3vich/tokenizer/internal/models"
+	"github.com/ruziba3vich/tokenizer/internal/pkg/helper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// passwordResetTTL is how long a reset code handed out by support stays valid
+const passwordResetTTL = 24 * time.Hour
+
+// uniqueViolation is the postgres error code of a unique index conflict
+const uniqueViolation = "23505"
+
+// UserFilter selects users; every set field must match
+type UserFilter struct {
+	// Query matches email, username or phone
+	Query    string
+	Email    string
+	Username string
+	Phone    string
+	// Page starts at 1; PageSize defaults to 50 and is capped at 200
+	Page, PageSize int
+}
+
+func (s *Service) GetUserByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.Preload("Plan").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SearchUsers returns a page of the users matching filter by case-insensitive
+// substring, ordered by id, and the number of all matches
+func (s *Service) SearchUsers(filter UserFilter) (*models.UserList, error) {
+	page, size := pagination(filter.Page, filter.PageSize)
+
+	query := s.db.Model(&models.User{})
+	if filter.Query != "" {
+		like := "%" + escapeLike(filter.Query) + "%"
+		query = query.Where("email ILIKE ? OR username ILIKE ? OR phone ILIKE ?", like, like, like)
+	}
+	for column, value := range map[string]string{"email": filter.Email, "username": filter.Username, "phone": filter.Phone} {
+		if value != "" {
+			query = query.Where(column+" ILIKE ?", "%"+escapeLike(value)+"%")
+		}
+	}
+
+	list := models.UserList{Users: []models.User{}, Page: page, PageSize: size}
+	if err := query.Session(&gorm.Session{}).Count(&list.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := query.Preload("Plan").Order("id").
+		Offset((page - 1) * size).Limit(size).
+		Find(&list.Users).Error; err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// CheckAccount returns why a user that proved their identity may still not sign in, or nil
+func CheckAccount(user *models.User) error {
+	switch {
+	case user.DisabledAt != nil:
+		return ErrUserDisabled
+	case user.PasswordResetRequired:
+		return ErrPasswordResetRequired
+	}
+	return nil
+}
+
+// DisableUser locks an account: logins are refused, its sessions end, its seats
+// are freed and its outstanding approvals are revoked
+func (s *Service) DisableUser(userID uint, reason string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		res := tx.Model(&models.User{ID: userID}).Where("disabled_at IS NULL").Update("disabled_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// either already disabled or missing; only the latter is an error
+			var user models.User
+			if err := tx.Select("id").First(&user, userID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrUserNotFound
+				}
+				return err
+			}
+			return nil
+		}
+
+		if err := revokeUserSessions(tx, userID, now); err != nil {
+			return err
+		}
+		if err := releaseUserLeases(tx, userID, now); err != nil {
+			return err
+		}
+		return tx.Create(&models.Revocation{UserID: &userID, Reason: reason}).Error
+	})
+}
+
+// EnableUser unlocks an account disabled by DisableUser
+func (s *Service) EnableUser(userID uint) error {
+	res := s.db.Model(&models.User{ID: userID}).Update("disabled_at", nil)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ForcePasswordReset ends the sessions of the user and blocks logins until a
+// new password is set with the returned code, which support hands to the user
+func (s *Service) ForcePasswordReset(userID uint) (string, time.Time, error) {
+	code, err := randomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(passwordResetTTL)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.User{ID: userID}).Updates(map[string]any{
+			"password_reset_required": true,
+			"password_reset_hash":     hashToken(code),
+			"password_reset_expires":  expiresAt,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return revokeUserSessions(tx, userID, now)
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return code, expiresAt, nil
+}
+
+// ResetPassword sets a new password with a code from ForcePasswordReset
+func (s *Service) ResetPassword(email, code, password string) error {
+	hashed, err := helper.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("email = ?", email).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetCode
+			}
+			return err
+		}
+		if !user.PasswordResetRequired || user.PasswordResetExpires == nil || !time.Now().Before(*user.PasswordResetExpires) ||
+			subtle.ConstantTimeCompare([]byte(user.PasswordResetHash), []byte(hashToken(code))) != 1 {
+			return ErrInvalidResetCode
+		}
+
+		return tx.Model(&user).Updates(map[string]any{
+			"password":                hashed,
+			"password_reset_required": false,
+			"password_reset_hash":     "",
+			"password_reset_expires":  nil,
+		}).Error
+	})
+}
+
+// UpdateUser applies the fields set in payload to the user and returns the updated user
+func (s *Service) UpdateUser(userID uint, payload *models.UpdateUserPayload) (*models.User, error) {
+	updates := map[string]any{}
+	if payload.FirstName != nil {
+		updates["first_name"] = strings.TrimSpace(*payload.FirstName)
+	}
+	if payload.LastName != nil {
+		updates["last_name"] = strings.TrimSpace(*payload.LastName)
+	}
+	if payload.Phone != nil {
+		updates["phone"] = strings.TrimSpace(*payload.Phone)
+	}
+	if payload.Username != nil {
+		username := strings.TrimSpace(*payload.Username)
+		var taken int64
+		if err := s.db.Model(&models.User{}).Where("username = ? AND id <> ?", username, userID).Count(&taken).Error; err != nil {
+			return nil, err
+		}
+		if taken > 0 {
+			return nil, ErrUsernameTaken
+		}
+		updates["username"] = username
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(&models.User{ID: userID}).Updates(updates).Error; err != nil {
+			// the check above races with concurrent updates; the unique index settles it
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+				return nil, ErrUsernameTaken
+			}
+			return nil, err
+		}
+	}
+	return s.GetUserByID(userID)
+}
+
+// SetUserRole changes the role of a user
+func (s *Service) SetUserRole(userID uint, role string) error {
+	if !models.ValidRole(role) {
+		return ErrUnknownRole
+	}
+	res := s.db.Model(&models.User{ID: userID}).Update("role", role)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteUser removes the account together with its devices, sessions and seat leases,
+// and revokes its outstanding approvals and licenses. Key redemptions and
+// revocations are kept as history
+func (s *Service) DeleteUser(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&models.Device{}, &models.RefreshToken{}, &models.Lease{}} {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		res := tx.Delete(&models.User{}, userID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return tx.Create(&models.Revocation{UserID: &userID, Reason: "account deleted"}).Error
+	})
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(v string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(v)
+}
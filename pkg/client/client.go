@@ -0,0 +1,380 @@
+// Package client logs in against the tokenizer server and verifies the signed
+// approvals and offline licenses it issues
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/pkg/signer"
+)
+
+const (
+	approvalType = "approval+jws"
+	licenseType  = "license+jws"
+	leaseType    = "lease+jws"
+	crlType      = "crl+jws"
+)
+
+// Client talks to one tokenizer server
+type Client struct {
+	baseURL    string
+	keys       KeySource
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithClock replaces time.Now, mainly for tests and clock skew handling
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) { c.now = now }
+}
+
+// New creates a client for the server at baseURL that trusts the keys of keys.
+// Use PinnedKey to trust a bundled public key or NewJWKS to follow the server's key set
+func New(baseURL string, keys KeySource, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		keys:       keys,
+		httpClient: http.DefaultClient,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Approval is a verified login approval
+type Approval struct {
+	// ID names the approval in the revocation list
+	ID        string
+	Status    string
+	UserID    uint
+	HWID      string
+	IssuedAt  time.Time
+	NotAfter  time.Time
+	Nonce     string
+	Challenge string
+	Plan      string
+	Features  []string
+	Limits    map[string]int
+	// LicenseExpiresAt is when the subscription ends; zero when it never does
+	LicenseExpiresAt time.Time
+	// RemainingDays counts down a trial; see Trial
+	RemainingDays int
+	// LeaseID is set when the login took a concurrent seat; keep it with Heartbeat
+	LeaseID        string
+	LeaseExpiresAt time.Time
+	KeyID          string
+	// Token is the compact JWS the approval was read from, suitable for caching
+	Token string
+	// Session is the access and refresh token pair issued by Login; it is not signed
+	Session *Session
+}
+
+type approvalClaims struct {
+	ID        string         `json:"jti"`
+	Status    string         `json:"status"`
+	UserID    uint           `json:"sub"`
+	Vhid      string         `json:"vhid"`
+	IssuedAt  int64          `json:"iat"`
+	NotAfter  int64          `json:"exp"`
+	Nonce     string         `json:"nonce"`
+	Challenge string         `json:"challenge,omitempty"`
+	Plan      string         `json:"plan,omitempty"`
+	Features  []string       `json:"features"`
+	Limits    map[string]int `json:"limits,omitempty"`
+	// LicenseExpiresAt is unix seconds, absent when the subscription never ends
+	LicenseExpiresAt int64  `json:"license_exp,omitempty"`
+	RemainingDays    int    `json:"remaining_days,omitempty"`
+	LeaseID          string `json:"lease_id,omitempty"`
+	LeaseExpiresAt   int64  `json:"lease_exp,omitempty"`
+}
+
+type loginRequest struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	Vhid      string `json:"hwid"`
+	Challenge string `json:"challenge,omitempty"`
+}
+
+// Login signs in from the machine identified by hwid and returns the verified
+// approval. A fresh challenge is sent with every call, so a replayed response is rejected
+func (c *Client) Login(ctx context.Context, email, password, hwid string) (*Approval, error) {
+	challengeBytes := make([]byte, 16)
+	if _, err := rand.Read(challengeBytes); err != nil {
+		return nil, err
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(challengeBytes)
+
+	var result apiResult
+	status, err := c.post(ctx, "/login", loginRequest{Email: email, Password: password, Vhid: hwid, Challenge: challenge}, &result)
+	if err != nil {
+		return nil, err
+	}
+	// refusals such as LICENSE_EXPIRED are signed too; anything else unsigned is an API error
+	if result.Token == "" {
+		return nil, &APIError{StatusCode: status, Message: result.Error}
+	}
+
+	approval, err := c.VerifyApproval(ctx, result.Token, hwid)
+	if approval == nil {
+		return nil, err
+	}
+	if approval.Challenge != challenge {
+		return nil, ErrChallengeMismatch
+	}
+	approval.Session = result.Session
+	return approval, err
+}
+
+// VerifyApproval checks a cached or received approval token: signature,
+// validity window, hwid binding and APPROVED or TRIAL status. For a correctly signed
+// refusal the approval is returned together with ErrLicenseExpired or ErrNotApproved
+func (c *Client) VerifyApproval(ctx context.Context, token, hwid string) (*Approval, error) {
+	header, payload, err := c.verifyToken(ctx, token, approvalType)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims approvalClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrTampered
+	}
+
+	approval := &Approval{
+		ID:            claims.ID,
+		Status:        claims.Status,
+		UserID:        claims.UserID,
+		HWID:          claims.Vhid,
+		IssuedAt:      time.Unix(claims.IssuedAt, 0),
+		NotAfter:      time.Unix(claims.NotAfter, 0),
+		Nonce:         claims.Nonce,
+		Challenge:     claims.Challenge,
+		Plan:          claims.Plan,
+		Features:      claims.Features,
+		Limits:        claims.Limits,
+		RemainingDays: claims.RemainingDays,
+		LeaseID:       claims.LeaseID,
+		KeyID:         header.Kid,
+		Token:         token,
+	}
+	if !c.now().Before(approval.NotAfter) {
+		return nil, ErrExpired
+	}
+	if approval.HWID != hwid {
+		return nil, ErrHWIDMismatch
+	}
+	if claims.LeaseExpiresAt != 0 {
+		approval.LeaseExpiresAt = time.Unix(claims.LeaseExpiresAt, 0)
+	}
+	if claims.LicenseExpiresAt != 0 {
+		approval.LicenseExpiresAt = time.Unix(claims.LicenseExpiresAt, 0)
+	}
+	switch approval.Status {
+	case "APPROVED", "TRIAL":
+		return approval, nil
+	case "LICENSE_EXPIRED":
+		return approval, ErrLicenseExpired
+	default:
+		return approval, ErrNotApproved
+	}
+}
+
+// Trial reports whether the account is on a trial; show RemainingDays and offer an upgrade
+func (a *Approval) Trial() bool {
+	return a.Status == "TRIAL"
+}
+
+// Lease is a verified concurrent seat lease
+type Lease struct {
+	ID        string
+	HWID      string
+	ExpiresAt time.Time
+}
+
+type leaseRequest struct {
+	LeaseID string `json:"lease_id"`
+	Vhid    string `json:"hwid"`
+}
+
+// Heartbeat renews the seat lease of an approval and returns its new expiry.
+// Call it well before LeaseExpiresAt; once a lease lapses a new Login is needed
+func (c *Client) Heartbeat(ctx context.Context, leaseID, hwid string) (*Lease, error) {
+	var result apiResult
+	status, err := c.post(ctx, "/lease/heartbeat", leaseRequest{LeaseID: leaseID, Vhid: hwid}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, &APIError{StatusCode: status, Message: result.Error}
+	}
+
+	_, payload, err := c.verifyToken(ctx, result.Token, leaseType)
+	if err != nil {
+		return nil, err
+	}
+	var claims struct {
+		LeaseID  string `json:"lease_id"`
+		Vhid     string `json:"vhid"`
+		NotAfter int64  `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.LeaseID != leaseID {
+		return nil, ErrTampered
+	}
+	if claims.Vhid != hwid {
+		return nil, ErrHWIDMismatch
+	}
+	lease := &Lease{ID: claims.LeaseID, HWID: claims.Vhid, ExpiresAt: time.Unix(claims.NotAfter, 0)}
+	if !c.now().Before(lease.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	return lease, nil
+}
+
+// Release gives the seat back, e.g. when the application exits
+func (c *Client) Release(ctx context.Context, leaseID, hwid string) error {
+	var result apiResult
+	status, err := c.post(ctx, "/lease/release", leaseRequest{LeaseID: leaseID, Vhid: hwid}, &result)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return &APIError{StatusCode: status, Message: result.Error}
+	}
+	return nil
+}
+
+type apiResult struct {
+	Token string `json:"token"`
+	Error string `json:"error"`
+	// Session is set on a successful login
+	Session *Session `json:"session"`
+}
+
+// post sends a JSON request and decodes the response into out, returning the status code
+func (c *Client) post(ctx context.Context, path string, body, out any) (int, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, &APIError{StatusCode: resp.StatusCode, Message: "undecodable response"}
+	}
+	return resp.StatusCode, nil
+}
+
+// HasFeature reports whether the approval grants feature
+func (a *Approval) HasFeature(feature string) bool {
+	return slices.Contains(a.Features, feature)
+}
+
+// License is a verified offline license
+type License struct {
+	ID           string   `json:"jti"`
+	UserID       uint     `json:"sub"`
+	Email        string   `json:"email"`
+	HWID         string   `json:"hwid"`
+	Entitlements []string `json:"entitlements"`
+	IssuedAt     int64    `json:"iat"`
+	NotBefore    int64    `json:"nbf"`
+	NotAfter     int64    `json:"exp"`
+}
+
+// VerifyLicense checks the content of an offline license file without any network access
+// when the client was built with PinnedKey
+func (c *Client) VerifyLicense(ctx context.Context, file []byte, hwid string) (*License, error) {
+	var doc struct {
+		Format string `json:"format"`
+		Token  string `json:"token"`
+	}
+	if err := json.Unmarshal(file, &doc); err != nil || doc.Format != "tokenizer-license/v1" {
+		return nil, errors.New("unsupported license format")
+	}
+
+	_, payload, err := c.verifyToken(ctx, doc.Token, licenseType)
+	if err != nil {
+		return nil, err
+	}
+
+	var lic License
+	if err := json.Unmarshal(payload, &lic); err != nil {
+		return nil, ErrTampered
+	}
+	now := c.now().Unix()
+	if now < lic.NotBefore || now >= lic.NotAfter {
+		return nil, ErrExpired
+	}
+	if lic.HWID != hwid {
+		return nil, ErrHWIDMismatch
+	}
+	return &lic, nil
+}
+
+// verifyToken checks the signature of a compact JWS of the expected type and returns its payload
+func (c *Client) verifyToken(ctx context.Context, token, typ string) (*signer.Header, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, ErrTampered
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, ErrTampered
+	}
+	var header signer.Header
+	if err := json.Unmarshal(rawHeader, &header); err != nil || header.Typ != typ {
+		return nil, nil, ErrTampered
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, ErrTampered
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, ErrTampered
+	}
+
+	alg, pub, err := c.keys.Key(ctx, header.Kid)
+	if errors.Is(err, ErrUnknownKey) {
+		return nil, nil, ErrTampered
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	// the algorithm belongs to the trusted key; a header claiming another one is forged
+	if header.Alg != alg {
+		return nil, nil, ErrTampered
+	}
+	if err := signer.Verify(alg, pub, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, nil, ErrTampered
+	}
+	return &header, payload, nil
+}
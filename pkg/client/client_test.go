@@ -0,0 +1,280 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ruziba3vich/tokenizer/internal/pkg/signer"
+)
+
+// testIssuer signs tokens the way the server does, with a key generated for the test
+type testIssuer struct {
+	kid    string
+	signer signer.Signer
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	s, err := signer.New(signer.EdDSA, key)
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+	return &testIssuer{kid: "test-key", signer: s}
+}
+
+// client trusts only the issuer's key and sees the clock stopped at now
+func (i *testIssuer) client(now time.Time) *Client {
+	return New("http://tokenizer.invalid", PinnedKey(i.kid, signer.EdDSA, i.signer.Public()),
+		WithClock(func() time.Time { return now }))
+}
+
+func (i *testIssuer) sign(t *testing.T, typ string, claims any) string {
+	t.Helper()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	token, err := signer.SignCompact(i.kid, i.signer, typ, payload)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return token
+}
+
+var testNow = time.Unix(1_750_000_000, 0)
+
+func testApprovalClaims() approvalClaims {
+	return approvalClaims{
+		ID:       "approval-1",
+		Status:   "APPROVED",
+		UserID:   7,
+		Vhid:     "hwid-1",
+		IssuedAt: testNow.Add(-time.Minute).Unix(),
+		NotAfter: testNow.Add(time.Minute).Unix(),
+		Nonce:    "nonce",
+		Plan:     "pro",
+		Features: []string{"export"},
+	}
+}
+
+func TestVerifyApprovalValid(t *testing.T) {
+	issuer := newTestIssuer(t)
+	token := issuer.sign(t, approvalType, testApprovalClaims())
+
+	approval, err := issuer.client(testNow).VerifyApproval(context.Background(), token, "hwid-1")
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if approval.ID != "approval-1" || approval.UserID != 7 || approval.KeyID != "test-key" || !approval.HasFeature("export") {
+		t.Errorf("approval = %+v", approval)
+	}
+}
+
+func TestVerifyApprovalRejects(t *testing.T) {
+	issuer := newTestIssuer(t)
+	valid := issuer.sign(t, approvalType, testApprovalClaims())
+	parts := strings.Split(valid, ".")
+
+	expired := testApprovalClaims()
+	expired.NotAfter = testNow.Unix()
+
+	forged := testApprovalClaims()
+	forged.UserID = 8
+	rawForged, _ := json.Marshal(forged)
+
+	otherIssuer := newTestIssuer(t)
+
+	// an RS256 header naming the pinned EdDSA key, signed with an RSA key the attacker holds
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	rsaSigner, _ := signer.New(signer.RS256, rsaKey)
+	confused := (&testIssuer{kid: "test-key", signer: rsaSigner}).sign(t, approvalType, testApprovalClaims())
+
+	tests := []struct {
+		name  string
+		token string
+		hwid  string
+		want  error
+	}{
+		{"tampered payload", parts[0] + "." + base64.RawURLEncoding.EncodeToString(rawForged) + "." + parts[2], "hwid-1", ErrTampered},
+		{"expired", issuer.sign(t, approvalType, expired), "hwid-1", ErrExpired},
+		{"wrong device", valid, "hwid-2", ErrHWIDMismatch},
+		{"unknown kid", (&testIssuer{kid: "other-key", signer: otherIssuer.signer}).sign(t, approvalType, testApprovalClaims()), "hwid-1", ErrTampered},
+		{"untrusted key with the trusted kid", otherIssuer.sign(t, approvalType, testApprovalClaims()), "hwid-1", ErrTampered},
+		{"lease token as approval", issuer.sign(t, leaseType, testApprovalClaims()), "hwid-1", ErrTampered},
+		{"license token as approval", issuer.sign(t, licenseType, testApprovalClaims()), "hwid-1", ErrTampered},
+		{"alg confusion", confused, "hwid-1", ErrTampered},
+		{"not a jws", "not.a-token", "hwid-1", ErrTampered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := issuer.client(testNow).VerifyApproval(context.Background(), tt.token, tt.hwid); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyApprovalSignedRefusal(t *testing.T) {
+	issuer := newTestIssuer(t)
+	claims := testApprovalClaims()
+	claims.Status = "LICENSE_EXPIRED"
+
+	approval, err := issuer.client(testNow).VerifyApproval(context.Background(), issuer.sign(t, approvalType, claims), "hwid-1")
+	if !errors.Is(err, ErrLicenseExpired) || approval == nil {
+		t.Errorf("verify = %v, %v; want the approval and %v", approval, err, ErrLicenseExpired)
+	}
+}
+
+func TestVerifyLicense(t *testing.T) {
+	issuer := newTestIssuer(t)
+	claims := License{
+		ID:        "license-1",
+		UserID:    7,
+		HWID:      "hwid-1",
+		IssuedAt:  testNow.Add(-time.Hour).Unix(),
+		NotBefore: testNow.Add(-time.Hour).Unix(),
+		NotAfter:  testNow.Add(time.Hour).Unix(),
+	}
+	file := func(token string) []byte {
+		data, _ := json.Marshal(map[string]string{"format": "tokenizer-license/v1", "token": token})
+		return data
+	}
+
+	lic, err := issuer.client(testNow).VerifyLicense(context.Background(), file(issuer.sign(t, licenseType, claims)), "hwid-1")
+	if err != nil || lic.ID != "license-1" {
+		t.Fatalf("verify = %+v, %v", lic, err)
+	}
+
+	tests := []struct {
+		name string
+		file []byte
+		hwid string
+		now  time.Time
+		want error
+	}{
+		{"approval token as license", file(issuer.sign(t, approvalType, claims)), "hwid-1", testNow, ErrTampered},
+		{"wrong device", file(issuer.sign(t, licenseType, claims)), "hwid-2", testNow, ErrHWIDMismatch},
+		{"expired", file(issuer.sign(t, licenseType, claims)), "hwid-1", testNow.Add(time.Hour), ErrExpired},
+		{"not yet valid", file(issuer.sign(t, licenseType, claims)), "hwid-1", testNow.Add(-2 * time.Hour), ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := issuer.client(tt.now).VerifyLicense(context.Background(), tt.file, tt.hwid); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWKSKeySource(t *testing.T) {
+	issuer := newTestIssuer(t)
+	ring := signer.NewKeyring()
+	if err := ring.Add(issuer.kid, issuer.signer); err != nil {
+		t.Fatalf("add key: %v", err)
+	}
+	set, err := ring.JWKS()
+	if err != nil {
+		t.Fatalf("jwks: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, NewJWKS(server.URL+"/.well-known/jwks.json", server.Client()),
+		WithClock(func() time.Time { return testNow }))
+	if _, err := c.VerifyApproval(context.Background(), issuer.sign(t, approvalType, testApprovalClaims()), "hwid-1"); err != nil {
+		t.Errorf("verify with the published key: %v", err)
+	}
+
+	stranger := &testIssuer{kid: "unpublished", signer: newTestIssuer(t).signer}
+	if _, err := c.VerifyApproval(context.Background(), stranger.sign(t, approvalType, testApprovalClaims()), "hwid-1"); !errors.Is(err, ErrTampered) {
+		t.Errorf("unpublished kid: err = %v, want %v", err, ErrTampered)
+	}
+}
+
+func TestRevocationListCheck(t *testing.T) {
+	issuer := newTestIssuer(t)
+	approval := &Approval{ID: "approval-1", UserID: 7, HWID: "hwid-1", IssuedAt: testNow.Add(-time.Minute)}
+	lic := &License{ID: "license-1", UserID: 7, HWID: "hwid-1", IssuedAt: testNow.Add(-time.Minute).Unix()}
+
+	type entry struct {
+		ApprovalID string `json:"jti,omitempty"`
+		UserID     uint   `json:"sub,omitempty"`
+		Vhid       string `json:"vhid,omitempty"`
+		RevokedAt  int64  `json:"revoked_at"`
+	}
+	tests := []struct {
+		name    string
+		entry   entry
+		revoked bool
+	}{
+		{"by approval id", entry{ApprovalID: "approval-1", RevokedAt: testNow.Unix()}, true},
+		{"by license id", entry{ApprovalID: "license-1", RevokedAt: testNow.Unix()}, true},
+		{"other id", entry{ApprovalID: "approval-2", RevokedAt: testNow.Unix()}, false},
+		{"by user", entry{UserID: 7, RevokedAt: testNow.Unix()}, true},
+		{"by device", entry{UserID: 7, Vhid: "hwid-1", RevokedAt: testNow.Unix()}, true},
+		{"other device", entry{UserID: 7, Vhid: "hwid-2", RevokedAt: testNow.Unix()}, false},
+		{"other user", entry{UserID: 8, RevokedAt: testNow.Unix()}, false},
+		{"revoked before issue", entry{UserID: 7, RevokedAt: testNow.Add(-time.Hour).Unix()}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := issuer.sign(t, crlType, map[string]any{
+				"iat":     testNow.Unix(),
+				"exp":     testNow.Add(5 * time.Minute).Unix(),
+				"revoked": []entry{tt.entry},
+			})
+			list, err := issuer.client(testNow).VerifyRevocationList(context.Background(), token)
+			if err != nil {
+				t.Fatalf("verify list: %v", err)
+			}
+
+			want := error(nil)
+			if tt.revoked {
+				want = ErrRevoked
+			}
+			if strings.HasPrefix(tt.entry.ApprovalID, "license") {
+				if err := list.CheckLicense(lic); !errors.Is(err, want) {
+					t.Errorf("check license = %v, want %v", err, want)
+				}
+				return
+			}
+			if err := list.Check(approval); !errors.Is(err, want) {
+				t.Errorf("check approval = %v, want %v", err, want)
+			}
+			if tt.entry.ApprovalID == "" {
+				if err := list.CheckLicense(lic); !errors.Is(err, want) {
+					t.Errorf("check license = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyRevocationListRejectsOtherTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	token := issuer.sign(t, approvalType, map[string]any{"iat": testNow.Unix(), "exp": testNow.Unix(), "revoked": []any{}})
+	if _, err := issuer.client(testNow).VerifyRevocationList(context.Background(), token); !errors.Is(err, ErrTampered) {
+		t.Errorf("approval token as list: err = %v, want %v", err, ErrTampered)
+	}
+}
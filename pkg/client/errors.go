@@ -0,0 +1,33 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTampered means the signature does not match the payload or the signing key is unknown
+	ErrTampered = errors.New("signature verification failed")
+	// ErrExpired means the approval or license is outside its validity window
+	ErrExpired = errors.New("approval expired")
+	// ErrHWIDMismatch means the approval was issued for another machine
+	ErrHWIDMismatch = errors.New("hwid mismatch")
+	// ErrChallengeMismatch means the approval does not echo the challenge sent with the login
+	ErrChallengeMismatch = errors.New("challenge mismatch")
+	// ErrLicenseExpired means the server signed a LICENSE_EXPIRED status: the subscription has lapsed
+	ErrLicenseExpired = errors.New("license expired")
+	// ErrNotApproved means the server signed a status other than APPROVED
+	ErrNotApproved = errors.New("login not approved")
+	// ErrRevoked means the approval or license is listed in the revocation list
+	ErrRevoked = errors.New("approval revoked")
+)
+
+// APIError is returned when the server answers with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/ruziba3vich/tokenizer/internal/pkg/signer"
+)
+
+// ErrUnknownKey is returned by a KeySource that has no key for the requested kid
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySource resolves the public key and algorithm a signature must be verified with
+type KeySource interface {
+	Key(ctx context.Context, kid string) (alg string, pub crypto.PublicKey, err error)
+}
+
+type pinnedKey struct {
+	kid string
+	alg string
+	pub crypto.PublicKey
+}
+
+// PinnedKey trusts a single public key. An empty kid accepts whatever kid the token names
+func PinnedKey(kid, alg string, pub crypto.PublicKey) KeySource {
+	return &pinnedKey{kid: kid, alg: alg, pub: pub}
+}
+
+func (p *pinnedKey) Key(_ context.Context, kid string) (string, crypto.PublicKey, error) {
+	if p.kid != "" && p.kid != kid {
+		return "", nil, ErrUnknownKey
+	}
+	return p.alg, p.pub, nil
+}
+
+// ParsePublicKeyPEM decodes a PKIX or PKCS#1 public key
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM format")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, errors.New("unsupported key type: " + block.Type)
+	}
+}
+
+// JWKS resolves keys from the server's /.well-known/jwks.json. The set is
+// cached and fetched again only when a token names a kid it does not know
+type JWKS struct {
+	url        string
+	httpClient *http.Client
+
+	mu   sync.Mutex
+	keys map[string]signer.JWK
+}
+
+func NewJWKS(url string, httpClient *http.Client) *JWKS {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &JWKS{url: url, httpClient: httpClient}
+}
+
+func (j *JWKS) Key(ctx context.Context, kid string) (string, crypto.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	jwk, ok := j.keys[kid]
+	if !ok {
+		if err := j.refresh(ctx); err != nil {
+			return "", nil, err
+		}
+		if jwk, ok = j.keys[kid]; !ok {
+			return "", nil, ErrUnknownKey
+		}
+	}
+
+	pub, err := jwk.PublicKey()
+	if err != nil {
+		return "", nil, err
+	}
+	return jwk.Alg, pub, nil
+}
+
+func (j *JWKS) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := j.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set signer.JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decoding jwks: %w", err)
+	}
+
+	j.keys = make(map[string]signer.JWK, len(set.Keys))
+	for _, key := range set.Keys {
+		j.keys[key.Kid] = key
+	}
+	return nil
+}
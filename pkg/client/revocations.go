@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// RevocationList is a verified list of approvals and licenses revoked before their expiry
+type RevocationList struct {
+	IssuedAt time.Time
+	// NextUpdate is when the server publishes a new list; the list may be cached until then
+	NextUpdate time.Time
+	Entries    []RevocationEntry
+	// Token is the compact JWS the list was read from, suitable for caching
+	Token string
+}
+
+// RevocationEntry revokes the approval ApprovalID, or every approval of UserID
+// (on HWID only, when set) issued at or before RevokedAt
+type RevocationEntry struct {
+	ApprovalID string
+	UserID     uint
+	HWID       string
+	RevokedAt  time.Time
+}
+
+type revocationList struct {
+	IssuedAt   int64 `json:"iat"`
+	NextUpdate int64 `json:"exp"`
+	Entries    []struct {
+		ApprovalID string `json:"jti"`
+		UserID     uint   `json:"sub"`
+		Vhid       string `json:"vhid"`
+		RevokedAt  int64  `json:"revoked_at"`
+	} `json:"revoked"`
+}
+
+// FetchRevocationList downloads and verifies the current revocation list
+func (c *Client) FetchRevocationList(ctx context.Context) (*RevocationList, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/revocations", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result apiResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: "undecodable response"}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: result.Error}
+	}
+	return c.VerifyRevocationList(ctx, result.Token)
+}
+
+// VerifyRevocationList checks the signature of a cached list token. A list past
+// NextUpdate is still returned; callers decide whether to fetch a fresh one
+func (c *Client) VerifyRevocationList(ctx context.Context, token string) (*RevocationList, error) {
+	_, payload, err := c.verifyToken(ctx, token, crlType)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims revocationList
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrTampered
+	}
+
+	list := &RevocationList{
+		IssuedAt:   time.Unix(claims.IssuedAt, 0),
+		NextUpdate: time.Unix(claims.NextUpdate, 0),
+		Entries:    make([]RevocationEntry, 0, len(claims.Entries)),
+		Token:      token,
+	}
+	for _, e := range claims.Entries {
+		list.Entries = append(list.Entries, RevocationEntry{
+			ApprovalID: e.ApprovalID,
+			UserID:     e.UserID,
+			HWID:       e.Vhid,
+			RevokedAt:  time.Unix(e.RevokedAt, 0),
+		})
+	}
+	return list, nil
+}
+
+// Stale reports whether a newer list has been published since this one
+func (l *RevocationList) Stale(now time.Time) bool {
+	return !now.Before(l.NextUpdate)
+}
+
+// Check returns ErrRevoked when an entry of the list covers the approval
+func (l *RevocationList) Check(a *Approval) error {
+	return l.check(a.ID, a.UserID, a.HWID, a.IssuedAt)
+}
+
+// CheckLicense returns ErrRevoked when an entry of the list covers the offline license
+func (l *RevocationList) CheckLicense(lic *License) error {
+	return l.check(lic.ID, lic.UserID, lic.HWID, time.Unix(lic.IssuedAt, 0))
+}
+
+func (l *RevocationList) check(id string, userID uint, hwid string, issuedAt time.Time) error {
+	for _, e := range l.Entries {
+		if e.ApprovalID != "" {
+			if e.ApprovalID == id {
+				return ErrRevoked
+			}
+			continue
+		}
+		if e.UserID == userID && (e.HWID == "" || e.HWID == hwid) && !issuedAt.After(e.RevokedAt) {
+			return ErrRevoked
+		}
+	}
+	return nil
+}
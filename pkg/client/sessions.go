@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Session is the access and refresh token pair issued at login
+type Session struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	// ExpiresIn is the lifetime of AccessToken in seconds
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh exchanges the refresh token for a new session. The old refresh
+// token stops working; always keep the one returned last
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*Session, error) {
+	var result struct {
+		Session
+		Error string `json:"error"`
+	}
+	status, err := c.post(ctx, "/token/refresh", refreshRequest{RefreshToken: refreshToken}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, &APIError{StatusCode: status, Message: result.Error}
+	}
+	return &result.Session, nil
+}
+
+// Logout revokes the session of the refresh token
+func (c *Client) Logout(ctx context.Context, refreshToken string) error {
+	var result apiResult
+	status, err := c.post(ctx, "/logout", refreshRequest{RefreshToken: refreshToken}, &result)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return &APIError{StatusCode: status, Message: result.Error}
+	}
+	return nil
+}